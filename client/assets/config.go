@@ -0,0 +1,201 @@
+package assets
+
+/*
+	Sliver Implant Framework
+	Copyright (C) 2019  Bishop Fox
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/term"
+)
+
+// ClientConfig - mirrors the server's console.ClientConfig JSON shape (kept as a separate
+// copy rather than importing server/console, the same way client/command/enroll's
+// enrolledConfig does, so the client binary never depends on server code)
+type ClientConfig struct {
+	Operator      string `json:"operator"`
+	LHost         string `json:"lhost"`
+	LPort         int    `json:"lport"`
+	CACertificate string `json:"ca_certificate"`
+	PrivateKey    string `json:"private_key"`
+	Certificate   string `json:"certificate"`
+}
+
+// Encrypted config container layout mirrors server/console's EncryptClientConfig /
+// EncryptClientConfigForRecipient: magic "SCFG", then a version byte selecting the KDF
+// (1 = argon2id passphrase, 2 = X25519 recipient key), then the KDF's own fields, then a
+// 24-byte nonce and a secretbox-sealed ciphertext.
+const (
+	encryptedConfigMagic     = "SCFG"
+	encryptedConfigVersionV1 = 1
+	encryptedConfigVersionV2 = 2
+
+	argon2SaltSize = 16
+	argon2KeySize  = 32
+
+	// maxArgon2Time/maxArgon2Memory - clamp KDF params read out of the file itself before
+	// deriving the key, so a planted file can't force an arbitrarily expensive argon2 run.
+	maxArgon2Time   = 10
+	maxArgon2Memory = 1024 * 1024 // 1 GiB, KiB units
+
+	recipientKeySize = 32
+)
+
+func isEncryptedClientConfig(data []byte) bool {
+	return len(data) >= len(encryptedConfigMagic) && string(data[:len(encryptedConfigMagic)]) == encryptedConfigMagic
+}
+
+// LoadClientConfig - read a ClientConfig from path, transparently decrypting it if it was
+// written by `new-player --encrypt`. Passphrase-encrypted configs prompt interactively;
+// recipient-key-encrypted configs are decrypted with recipientPrivateKey, which may be nil
+// if the config isn't recipient-encrypted.
+//
+// NOTE: the sliver-client entrypoint that reads `--config`/`-N` and calls this on startup
+// (e.g. a cmd/sliver-client main.go or client/console bootstrap) is not present in this
+// checkout; wire this in there once it exists.
+func LoadClientConfig(path string, recipientPrivateKey *[32]byte) (*ClientConfig, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if isEncryptedClientConfig(raw) {
+		raw, err = decryptClientConfig(path, raw, recipientPrivateKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	config := &ClientConfig{}
+	if err := json.Unmarshal(raw, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func decryptClientConfig(path string, container []byte, recipientPrivateKey *[32]byte) ([]byte, error) {
+	if len(container) < len(encryptedConfigMagic)+1 {
+		return nil, fmt.Errorf("encrypted config is truncated")
+	}
+	version := container[len(encryptedConfigMagic)]
+
+	switch version {
+	case encryptedConfigVersionV1:
+		passphrase, err := promptPassphrase(fmt.Sprintf("Passphrase for %s: ", path))
+		if err != nil {
+			return nil, err
+		}
+		return decryptWithPassphrase(container, passphrase)
+	case encryptedConfigVersionV2:
+		if recipientPrivateKey == nil {
+			return nil, fmt.Errorf("%s is encrypted for a recipient key, pass --recipient-key", path)
+		}
+		return decryptWithRecipientKey(container, *recipientPrivateKey)
+	default:
+		return nil, fmt.Errorf("unsupported encrypted config version %d", version)
+	}
+}
+
+func decryptWithPassphrase(container []byte, passphrase string) ([]byte, error) {
+	offset := len(encryptedConfigMagic) + 1
+	headerLen := offset + 4 + 4 + 1 + argon2SaltSize + 24
+	if len(container) < headerLen {
+		return nil, fmt.Errorf("encrypted config is truncated")
+	}
+
+	argonTime := binary.BigEndian.Uint32(container[offset : offset+4])
+	offset += 4
+	argonMemory := binary.BigEndian.Uint32(container[offset : offset+4])
+	offset += 4
+	argonThreads := container[offset]
+	offset++
+
+	if argonTime > maxArgon2Time {
+		argonTime = maxArgon2Time
+	}
+	if argonMemory > maxArgon2Memory {
+		argonMemory = maxArgon2Memory
+	}
+
+	salt := container[offset : offset+argon2SaltSize]
+	offset += argon2SaltSize
+
+	var nonce [24]byte
+	copy(nonce[:], container[offset:offset+24])
+	offset += 24
+
+	ciphertext := container[offset:]
+
+	key := argon2.IDKey([]byte(passphrase), salt, argonTime, argonMemory, argonThreads, argon2KeySize)
+	var secretKey [32]byte
+	copy(secretKey[:], key)
+
+	plaintext, ok := secretbox.Open(nil, ciphertext, &nonce, &secretKey)
+	if !ok {
+		return nil, fmt.Errorf("decryption failed: wrong passphrase or corrupt config")
+	}
+	return plaintext, nil
+}
+
+func decryptWithRecipientKey(container []byte, recipientPrivateKey [recipientKeySize]byte) ([]byte, error) {
+	offset := len(encryptedConfigMagic) + 1
+	headerLen := offset + recipientKeySize + 24
+	if len(container) < headerLen {
+		return nil, fmt.Errorf("encrypted config is truncated")
+	}
+
+	var ephemeralPublic [recipientKeySize]byte
+	copy(ephemeralPublic[:], container[offset:offset+recipientKeySize])
+	offset += recipientKeySize
+
+	var nonce [24]byte
+	copy(nonce[:], container[offset:offset+24])
+	offset += 24
+
+	ciphertext := container[offset:]
+
+	shared, err := curve25519.X25519(recipientPrivateKey[:], ephemeralPublic[:])
+	if err != nil {
+		return nil, fmt.Errorf("X25519 exchange failed: %w", err)
+	}
+	secretKey := sha256.Sum256(shared)
+
+	plaintext, ok := secretbox.Open(nil, ciphertext, &nonce, &secretKey)
+	if !ok {
+		return nil, fmt.Errorf("decryption failed: wrong recipient key or corrupt config")
+	}
+	return plaintext, nil
+}
+
+// promptPassphrase - read a passphrase from the terminal without echoing it
+func promptPassphrase(prompt string) (string, error) {
+	fmt.Print(prompt)
+	passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+	return string(passphrase), nil
+}
@@ -21,7 +21,6 @@ package c2profiles
 import (
 	"context"
 	"encoding/json"
-	"io"
 	"os"
 	"strings"
 
@@ -49,17 +48,25 @@ func C2ProfileCmd(cmd *cobra.Command, con *console.SliverConsoleClient, args []s
 
 func ImportC2ProfileCmd(cmd *cobra.Command, con *console.SliverConsoleClient, args []string) {
 	profileName, _ := cmd.Flags().GetString("name")
-	filepath, _ := cmd.Flags().GetString("file")
+	configPath, _ := cmd.Flags().GetString("file")
+
+	format, err := resolveC2ProfileFormat(cmd, configPath)
+	if err != nil {
+		con.PrintErrorf("%s\n", err)
+		return
+	}
 
 	// retrieve and unmarshal profile config
-	jsonFile, err := os.Open(filepath)
+	byteFile, err := os.ReadFile(configPath)
 	if err != nil {
 		con.PrintErrorf("%s\n", err)
 		return
 	}
-	byteFile, _ := io.ReadAll(jsonFile)
-	var config *configs.HTTPC2Config = &configs.HTTPC2Config{}
-	json.Unmarshal(byteFile, config)
+	config, err := decodeC2Config(byteFile, format)
+	if err != nil {
+		con.PrintErrorf("failed to parse %s: %s\n", format, err)
+		return
+	}
 	_, err = con.Rpc.SaveHTTPC2Profile(context.Background(), C2ConfigToProtobuf(profileName, config))
 	if err != nil {
 		con.PrintErrorf("%s\n", err)
@@ -67,11 +74,196 @@ func ImportC2ProfileCmd(cmd *cobra.Command, con *console.SliverConsoleClient, ar
 	}
 }
 
+// ExportC2ProfileCmd - fetch a stored profile and write it back out as a configs.HTTPC2Config,
+// encoded as JSON, YAML or TOML per --format or the --file extension (JSON by default with --stdout)
+func ExportC2ProfileCmd(cmd *cobra.Command, con *console.SliverConsoleClient, args []string) {
+	profileName, _ := cmd.Flags().GetString("name")
+	configPath, _ := cmd.Flags().GetString("file")
+	toStdout, _ := cmd.Flags().GetBool("stdout")
+
+	profile, err := con.Rpc.GetHTTPC2ProfileByName(context.Background(), &clientpb.C2ProfileReq{Name: profileName})
+	if err != nil {
+		con.PrintErrorf("%s\n", err)
+		return
+	}
+	config := ProtobufToC2Config(profile)
+
+	format := formatJSON
+	if toStdout {
+		explicit, _ := cmd.Flags().GetString("format")
+		if explicit != "" {
+			format = c2ProfileFormat(strings.ToLower(explicit))
+		}
+	} else {
+		if configPath == "" {
+			con.PrintErrorf("must specify --file or --stdout\n")
+			return
+		}
+		format, err = resolveC2ProfileFormat(cmd, configPath)
+		if err != nil {
+			con.PrintErrorf("%s\n", err)
+			return
+		}
+	}
+
+	encoded, err := encodeC2Config(config, format)
+	if err != nil {
+		con.PrintErrorf("%s\n", err)
+		return
+	}
+
+	if toStdout {
+		con.Println(string(encoded))
+		return
+	}
+	err = os.WriteFile(configPath, encoded, 0o600)
+	if err != nil {
+		con.PrintErrorf("%s\n", err)
+		return
+	}
+	con.PrintInfof("Exported profile %s to %s\n", profileName, configPath)
+}
+
+// ProtobufToC2Config - convert protobuf back into configs.HTTPC2Config, the inverse of C2ConfigToProtobuf
+func ProtobufToC2Config(profile *clientpb.HTTPC2Config) *configs.HTTPC2Config {
+	implant := profile.ImplantConfig
+
+	urlParameters := []configs.HTTPC2URLParameter{}
+	for _, p := range implant.ExtraURLParameters {
+		urlParameters = append(urlParameters, configs.HTTPC2URLParameter{
+			Method:      p.Method,
+			Name:        p.Name,
+			Value:       p.Value,
+			Probability: int(p.Probability),
+		})
+	}
+
+	headers := []configs.HTTPC2Header{}
+	for _, h := range implant.Headers {
+		headers = append(headers, configs.HTTPC2Header{
+			Method:      h.Method,
+			Name:        h.Name,
+			Value:       h.Value,
+			Probability: int(h.Probability),
+		})
+	}
+
+	var (
+		pollPaths    []string
+		pollFiles    []string
+		sessionPaths []string
+		sessionFiles []string
+		closePaths   []string
+		closeFiles   []string
+		stagerPaths  []string
+		stagerFiles  []string
+	)
+	for _, segment := range implant.PathSegments {
+		switch {
+		case segment.IsFile && segment.SegmentType == 0:
+			pollFiles = append(pollFiles, segment.Value)
+		case segment.IsFile && segment.SegmentType == 1:
+			sessionFiles = append(sessionFiles, segment.Value)
+		case segment.IsFile && segment.SegmentType == 2:
+			closeFiles = append(closeFiles, segment.Value)
+		case segment.IsFile && segment.SegmentType == 3:
+			stagerFiles = append(stagerFiles, segment.Value)
+		case !segment.IsFile && segment.SegmentType == 0:
+			pollPaths = append(pollPaths, segment.Value)
+		case !segment.IsFile && segment.SegmentType == 1:
+			sessionPaths = append(sessionPaths, segment.Value)
+		case !segment.IsFile && segment.SegmentType == 2:
+			closePaths = append(closePaths, segment.Value)
+		case !segment.IsFile && segment.SegmentType == 3:
+			stagerPaths = append(stagerPaths, segment.Value)
+		}
+	}
+
+	implantConfig := configs.HTTPC2ImplantConfig{
+		UserAgent:           implant.UserAgent,
+		ChromeBaseVersion:   int(implant.ChromeBaseVersion),
+		MacOSVersion:        implant.MacOSVersion,
+		NonceQueryArgChars:  implant.NonceQueryArgChars,
+		ExtraURLParameters:  urlParameters,
+		Headers:             headers,
+		MaxFiles:            int(implant.MaxFiles),
+		MinFiles:            int(implant.MinFiles),
+		MaxPaths:            int(implant.MaxPaths),
+		MinPaths:            int(implant.MinPaths),
+		StagerFileExt:       implant.StagerFileExtension,
+		PollFileExt:         implant.PollFileExtension,
+		StartSessionFileExt: implant.StartSessionFileExtension,
+		SessionFileExt:      implant.SessionFileExtension,
+		CloseFileExt:        implant.CloseFileExtension,
+		PollPaths:           pollPaths,
+		PollFiles:           pollFiles,
+		SessionPaths:        sessionPaths,
+		SessionFiles:        sessionFiles,
+		ClosePaths:          closePaths,
+		CloseFiles:          closeFiles,
+		StagerPaths:         stagerPaths,
+		StagerFiles:         stagerFiles,
+	}
+
+	// The forward conversion explodes one server header per method, so group
+	// them back together by name+value to recover the original Methods list.
+	serverHeaders := []configs.HTTPC2Header{}
+	serverHeaderIndex := map[string]int{}
+	for _, h := range profile.ServerConfig.Headers {
+		key := h.Name + "\x00" + h.Value
+		if idx, ok := serverHeaderIndex[key]; ok {
+			serverHeaders[idx].Methods = append(serverHeaders[idx].Methods, h.Method)
+			continue
+		}
+		serverHeaderIndex[key] = len(serverHeaders)
+		serverHeaders = append(serverHeaders, configs.HTTPC2Header{
+			Methods:     []string{h.Method},
+			Name:        h.Name,
+			Value:       h.Value,
+			Probability: int(h.Probability),
+		})
+	}
+
+	serverCookies := []string{}
+	for _, c := range profile.ServerConfig.Cookies {
+		serverCookies = append(serverCookies, c.Name)
+	}
+
+	serverConfig := configs.HTTPC2ServerConfig{
+		RandomVersionHeaders: profile.ServerConfig.RandomVersionHeaders,
+		Headers:              serverHeaders,
+		Cookies:              serverCookies,
+	}
+
+	return &configs.HTTPC2Config{
+		ImplantConfig: implantConfig,
+		ServerConfig:  serverConfig,
+	}
+}
+
 // convert json to protobuf
 func C2ConfigToProtobuf(profileName string, config *configs.HTTPC2Config) *clientpb.HTTPC2Config {
 
 	httpC2UrlParameters := []*clientpb.HTTPC2URLParameter{}
+	for _, urlParam := range config.ImplantConfig.ExtraURLParameters {
+		httpC2UrlParameters = append(httpC2UrlParameters, &clientpb.HTTPC2URLParameter{
+			Method:      urlParam.Method,
+			Name:        urlParam.Name,
+			Value:       urlParam.Value,
+			Probability: int32(urlParam.Probability),
+		})
+	}
+
 	httpC2Headers := []*clientpb.HTTPC2Header{}
+	for _, header := range config.ImplantConfig.Headers {
+		httpC2Headers = append(httpC2Headers, &clientpb.HTTPC2Header{
+			Method:      header.Method,
+			Name:        header.Name,
+			Value:       header.Value,
+			Probability: int32(header.Probability),
+		})
+	}
+
 	pathSegments := []*clientpb.HTTPC2PathSegment{}
 
 	// files
@@ -150,7 +342,7 @@ func C2ConfigToProtobuf(profileName string, config *configs.HTTPC2Config) *clien
 		MaxFiles:                  int32(config.ImplantConfig.MaxFiles),
 		MinFiles:                  int32(config.ImplantConfig.MinFiles),
 		MaxPaths:                  int32(config.ImplantConfig.MaxPaths),
-		MinPaths:                  int32(config.ImplantConfig.MinFiles),
+		MinPaths:                  int32(config.ImplantConfig.MinPaths),
 		StagerFileExtension:       config.ImplantConfig.StagerFileExt,
 		PollFileExtension:         config.ImplantConfig.PollFileExt,
 		StartSessionFileExtension: config.ImplantConfig.StartSessionFileExt,
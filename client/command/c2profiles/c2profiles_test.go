@@ -0,0 +1,77 @@
+package c2profiles
+
+/*
+	Sliver Implant Framework
+	Copyright (C) 2019  Bishop Fox
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bishopfox/sliver/server/configs"
+)
+
+// TestC2ConfigRoundTrip guards the import->export->import invariant this package exists to
+// guarantee: converting a configs.HTTPC2Config to protobuf and back must reproduce it exactly.
+// MaxPaths/MinPaths got distinct values on purpose, to catch the MinPaths/MinFiles mixup this
+// test was added for.
+func TestC2ConfigRoundTrip(t *testing.T) {
+	original := &configs.HTTPC2Config{
+		ImplantConfig: configs.HTTPC2ImplantConfig{
+			UserAgent:          "Mozilla/5.0",
+			ChromeBaseVersion:  100,
+			MacOSVersion:       "10_15_7",
+			NonceQueryArgChars: "abcdefg",
+			ExtraURLParameters: []configs.HTTPC2URLParameter{
+				{Method: "GET", Name: "q", Value: "1", Probability: 50},
+			},
+			Headers: []configs.HTTPC2Header{
+				{Method: "GET", Name: "X-Test", Value: "v", Probability: 25},
+			},
+			MaxFiles:            10,
+			MinFiles:            5,
+			MaxPaths:            8,
+			MinPaths:            3,
+			StagerFileExt:       "woff",
+			PollFileExt:         "js",
+			StartSessionFileExt: "png",
+			SessionFileExt:      "css",
+			CloseFileExt:        "html",
+			PollPaths:           []string{"poll-path"},
+			PollFiles:           []string{"poll-file"},
+			SessionPaths:        []string{"session-path"},
+			SessionFiles:        []string{"session-file"},
+			ClosePaths:          []string{"close-path"},
+			CloseFiles:          []string{"close-file"},
+			StagerPaths:         []string{"stager-path"},
+			StagerFiles:         []string{"stager-file"},
+		},
+		ServerConfig: configs.HTTPC2ServerConfig{
+			RandomVersionHeaders: true,
+			Headers: []configs.HTTPC2Header{
+				{Methods: []string{"GET", "POST"}, Name: "Server", Value: "nginx", Probability: 100},
+			},
+			Cookies: []string{"session-id"},
+		},
+	}
+
+	roundTripped := ProtobufToC2Config(C2ConfigToProtobuf("test-profile", original))
+
+	if !reflect.DeepEqual(original, roundTripped) {
+		t.Fatalf("round trip did not reproduce the original config\nwant: %+v\ngot:  %+v", original, roundTripped)
+	}
+}
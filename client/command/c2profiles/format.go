@@ -0,0 +1,148 @@
+package c2profiles
+
+/*
+	Sliver Implant Framework
+	Copyright (C) 2019  Bishop Fox
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/bishopfox/sliver/client/console"
+	"github.com/bishopfox/sliver/server/configs"
+)
+
+// c2ProfileFormat - markup format used to (de)serialize a configs.HTTPC2Config on disk
+type c2ProfileFormat string
+
+const (
+	formatJSON c2ProfileFormat = "json"
+	formatYAML c2ProfileFormat = "yaml"
+	formatTOML c2ProfileFormat = "toml"
+)
+
+// formatFromExt - guess the format from a file's extension
+func formatFromExt(path string) (c2ProfileFormat, bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return formatJSON, true
+	case ".yaml", ".yml":
+		return formatYAML, true
+	case ".toml":
+		return formatTOML, true
+	default:
+		return "", false
+	}
+}
+
+// resolveC2ProfileFormat - an explicit --format flag wins, otherwise fall back to the file extension
+func resolveC2ProfileFormat(cmd *cobra.Command, filepath string) (c2ProfileFormat, error) {
+	explicit, _ := cmd.Flags().GetString("format")
+	if explicit != "" {
+		switch c2ProfileFormat(strings.ToLower(explicit)) {
+		case formatJSON, formatYAML, formatTOML:
+			return c2ProfileFormat(strings.ToLower(explicit)), nil
+		default:
+			return "", fmt.Errorf("unsupported format %q (want json, yaml, or toml)", explicit)
+		}
+	}
+	format, ok := formatFromExt(filepath)
+	if !ok {
+		return "", fmt.Errorf("could not determine format from file extension %q, pass --format", filepath)
+	}
+	return format, nil
+}
+
+// decodeC2Config - unmarshal raw bytes into a configs.HTTPC2Config according to format
+func decodeC2Config(data []byte, format c2ProfileFormat) (*configs.HTTPC2Config, error) {
+	config := &configs.HTTPC2Config{}
+	var err error
+	switch format {
+	case formatJSON:
+		err = json.Unmarshal(data, config)
+	case formatYAML:
+		err = yaml.Unmarshal(data, config)
+	case formatTOML:
+		err = toml.Unmarshal(data, config)
+	default:
+		err = fmt.Errorf("unsupported format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// encodeC2Config - marshal a configs.HTTPC2Config according to format
+func encodeC2Config(config *configs.HTTPC2Config, format c2ProfileFormat) ([]byte, error) {
+	switch format {
+	case formatJSON:
+		return json.MarshalIndent(config, "", "  ")
+	case formatYAML:
+		return yaml.Marshal(config)
+	case formatTOML:
+		return toml.Marshal(config)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// ConvertC2ProfileCmd - read a profile in one markup format and write it out in another, without touching the server
+func ConvertC2ProfileCmd(cmd *cobra.Command, con *console.SliverConsoleClient, args []string) {
+	inFile, _ := cmd.Flags().GetString("in")
+	outFile, _ := cmd.Flags().GetString("out")
+
+	inFormat, err := resolveC2ProfileFormat(cmd, inFile)
+	if err != nil {
+		con.PrintErrorf("%s\n", err)
+		return
+	}
+	outFormat, ok := formatFromExt(outFile)
+	if !ok {
+		con.PrintErrorf("could not determine output format from file extension %q\n", outFile)
+		return
+	}
+
+	raw, err := os.ReadFile(inFile)
+	if err != nil {
+		con.PrintErrorf("%s\n", err)
+		return
+	}
+	config, err := decodeC2Config(raw, inFormat)
+	if err != nil {
+		con.PrintErrorf("failed to parse %s: %s\n", inFormat, err)
+		return
+	}
+	out, err := encodeC2Config(config, outFormat)
+	if err != nil {
+		con.PrintErrorf("failed to render %s: %s\n", outFormat, err)
+		return
+	}
+	err = os.WriteFile(outFile, out, 0o600)
+	if err != nil {
+		con.PrintErrorf("%s\n", err)
+		return
+	}
+	con.PrintInfof("Converted %s (%s) -> %s (%s)\n", inFile, inFormat, outFile, outFormat)
+}
@@ -0,0 +1,210 @@
+package c2profiles
+
+/*
+	Sliver Implant Framework
+	Copyright (C) 2019  Bishop Fox
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+
+	"github.com/bishopfox/sliver/client/command/settings"
+	"github.com/bishopfox/sliver/client/console"
+	"github.com/bishopfox/sliver/protobuf/clientpb"
+	"github.com/bishopfox/sliver/server/db/models"
+)
+
+// C2ProfileListCmd - list every stored HTTP C2 profile
+func C2ProfileListCmd(cmd *cobra.Command, con *console.SliverConsoleClient, args []string) {
+	profiles, err := con.Rpc.GetHTTPC2Profiles(context.Background(), &clientpb.Empty{})
+	if err != nil {
+		con.PrintErrorf("%s\n", err)
+		return
+	}
+	if len(profiles.Configs) == 0 {
+		con.PrintInfof("No C2 profiles\n")
+		return
+	}
+
+	tw := table.NewWriter()
+	tw.SetStyle(settings.GetTableStyle(con))
+	tw.AppendHeader(table.Row{
+		"Name",
+		"Headers",
+		"Cookies",
+		"Poll paths",
+		"Session paths",
+		"Close paths",
+		"Last Modified",
+	})
+	for _, profile := range profiles.Configs {
+		model := models.HTTPC2ConfigFromProtobuf(profile)
+		var pollPaths, sessionPaths, closePaths int
+		for _, segment := range model.ImplantConfig.PathSegments {
+			if segment.IsFile {
+				continue
+			}
+			switch segment.SegmentType {
+			case 0:
+				pollPaths++
+			case 1:
+				sessionPaths++
+			case 2:
+				closePaths++
+			}
+		}
+		tw.AppendRow(table.Row{
+			model.Name,
+			len(model.ServerConfig.Headers) + len(model.ImplantConfig.Headers),
+			len(model.ServerConfig.Cookies),
+			pollPaths,
+			sessionPaths,
+			closePaths,
+			model.LastUpdatedAt,
+		})
+	}
+	con.Println(tw.Render())
+}
+
+// C2ProfileRmCmd - delete a stored HTTP C2 profile
+func C2ProfileRmCmd(cmd *cobra.Command, con *console.SliverConsoleClient, args []string) {
+	profileName, _ := cmd.Flags().GetString("name")
+	if profileName == "" {
+		con.PrintErrorf("must specify --name\n")
+		return
+	}
+
+	confirm := false
+	prompt := &survey.Confirm{Message: fmt.Sprintf("Delete C2 profile %q?", profileName)}
+	survey.AskOne(prompt, &confirm)
+	if !confirm {
+		return
+	}
+
+	_, err := con.Rpc.DeleteHTTPC2Profile(context.Background(), &clientpb.C2ProfileReq{Name: profileName})
+	if err != nil {
+		con.PrintErrorf("%s\n", err)
+		return
+	}
+	con.PrintInfof("Deleted C2 profile %s\n", profileName)
+}
+
+// C2ProfileDiffCmd - print a field-by-field diff between two stored HTTP C2 profiles
+func C2ProfileDiffCmd(cmd *cobra.Command, con *console.SliverConsoleClient, args []string) {
+	nameA, _ := cmd.Flags().GetString("a")
+	nameB, _ := cmd.Flags().GetString("b")
+	if nameA == "" || nameB == "" {
+		con.PrintErrorf("must specify both --a and --b\n")
+		return
+	}
+
+	profileA, err := con.Rpc.GetHTTPC2ProfileByName(context.Background(), &clientpb.C2ProfileReq{Name: nameA})
+	if err != nil {
+		con.PrintErrorf("%s\n", err)
+		return
+	}
+	profileB, err := con.Rpc.GetHTTPC2ProfileByName(context.Background(), &clientpb.C2ProfileReq{Name: nameB})
+	if err != nil {
+		con.PrintErrorf("%s\n", err)
+		return
+	}
+
+	diffs := diffC2Profiles(models.HTTPC2ConfigFromProtobuf(profileA), models.HTTPC2ConfigFromProtobuf(profileB))
+	if len(diffs) == 0 {
+		con.PrintInfof("No differences between %s and %s\n", nameA, nameB)
+		return
+	}
+
+	tw := table.NewWriter()
+	tw.SetStyle(settings.GetTableStyle(con))
+	tw.AppendHeader(table.Row{"Field", nameA, nameB})
+	for _, d := range diffs {
+		tw.AppendRow(table.Row{d.field, con.Red(d.a), con.Green(d.b)})
+	}
+	con.Println(tw.Render())
+}
+
+type c2ProfileFieldDiff struct {
+	field string
+	a     string
+	b     string
+}
+
+// diffC2Profiles - flatten the two profiles into comparable fields and return only the ones that differ
+func diffC2Profiles(a *models.HTTPC2Config, b *models.HTTPC2Config) []c2ProfileFieldDiff {
+	diffs := []c2ProfileFieldDiff{}
+
+	addIfDiff := func(field, valA, valB string) {
+		if valA != valB {
+			diffs = append(diffs, c2ProfileFieldDiff{field: field, a: valA, b: valB})
+		}
+	}
+
+	addIfDiff("User Agent", a.ImplantConfig.UserAgent, b.ImplantConfig.UserAgent)
+	addIfDiff("Chrome base version", fmt.Sprintf("%d", a.ImplantConfig.ChromeBaseVersion), fmt.Sprintf("%d", b.ImplantConfig.ChromeBaseVersion))
+	addIfDiff("MacOS version", a.ImplantConfig.MacOSVersion, b.ImplantConfig.MacOSVersion)
+	addIfDiff("Randomize Server Headers", fmt.Sprintf("%v", a.ServerConfig.RandomVersionHeaders), fmt.Sprintf("%v", b.ServerConfig.RandomVersionHeaders))
+	addIfDiff("Server Headers", joinHeaders(a.ServerConfig.Headers), joinHeaders(b.ServerConfig.Headers))
+	addIfDiff("Server Cookies", joinCookies(a.ServerConfig.Cookies), joinCookies(b.ServerConfig.Cookies))
+	addIfDiff("Client Headers", joinHeaders(a.ImplantConfig.Headers), joinHeaders(b.ImplantConfig.Headers))
+	addIfDiff("Extra URL Parameters", joinURLParams(a.ImplantConfig.ExtraURLParameters), joinURLParams(b.ImplantConfig.ExtraURLParameters))
+
+	for segType, label := range map[int]string{0: "Poll", 1: "Session", 2: "Close", 3: "Stager"} {
+		addIfDiff(label+" paths", joinSegments(a.ImplantConfig.PathSegments, segType, false), joinSegments(b.ImplantConfig.PathSegments, segType, false))
+		addIfDiff(label+" files", joinSegments(a.ImplantConfig.PathSegments, segType, true), joinSegments(b.ImplantConfig.PathSegments, segType, true))
+	}
+
+	return diffs
+}
+
+func joinHeaders(headers []models.HTTPC2Header) string {
+	values := []string{}
+	for _, h := range headers {
+		values = append(values, h.Name+"="+h.Value)
+	}
+	return fmt.Sprintf("%v", values)
+}
+
+func joinCookies(cookies []models.HTTPC2Cookie) string {
+	values := []string{}
+	for _, c := range cookies {
+		values = append(values, c.Name)
+	}
+	return fmt.Sprintf("%v", values)
+}
+
+func joinURLParams(params []models.HTTPC2URLParameter) string {
+	values := []string{}
+	for _, p := range params {
+		values = append(values, p.Name+"="+p.Value)
+	}
+	return fmt.Sprintf("%v", values)
+}
+
+func joinSegments(segments []models.HTTPC2PathSegment, segmentType int, isFile bool) string {
+	values := []string{}
+	for _, s := range segments {
+		if s.SegmentType == segmentType && s.IsFile == isFile {
+			values = append(values, s.Value)
+		}
+	}
+	return fmt.Sprintf("%v", values)
+}
@@ -0,0 +1,203 @@
+package enroll
+
+/*
+	Sliver Implant Framework
+	Copyright (C) 2019  Bishop Fox
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+)
+
+// enrolledConfig - mirrors the server's console.ClientConfig JSON shape
+type enrolledConfig struct {
+	Operator      string `json:"operator"`
+	LHost         string `json:"lhost"`
+	LPort         int    `json:"lport"`
+	CACertificate string `json:"ca_certificate"`
+	PrivateKey    string `json:"private_key"`
+	Certificate   string `json:"certificate"`
+}
+
+type enrollmentRequest struct {
+	Token string `json:"token"`
+}
+
+// EnrollCmd - trade a short-lived enrollment token for a ClientConfig, writing it
+// to the standard config directory instead of hand-carrying a .cfg file. The server's
+// self-signed cert can't be verified against a system root, so the first connection
+// does trust-on-first-use: the operator confirms the cert's fingerprint out-of-band
+// (e.g. with the server's administrator) or passes it via --fingerprint, and every
+// subsequent request in this invocation is pinned to that same fingerprint.
+func EnrollCmd(cmd *cobra.Command, args []string) error {
+	enrollURL, _ := cmd.Flags().GetString("url")
+	token, _ := cmd.Flags().GetString("token")
+	save, _ := cmd.Flags().GetString("save")
+	expectedFingerprint, _ := cmd.Flags().GetString("fingerprint")
+	allowInsecure, _ := cmd.Flags().GetBool("allow-insecure")
+
+	if enrollURL == "" || token == "" {
+		return fmt.Errorf("--url and --token are both required")
+	}
+
+	_, fingerprint, err := probeServerFingerprint(enrollURL)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case expectedFingerprint != "":
+		if !fingerprintsEqual(expectedFingerprint, fingerprint) {
+			return fmt.Errorf("server certificate fingerprint %s does not match expected %s", fingerprint, expectedFingerprint)
+		}
+	case allowInsecure:
+		// --allow-insecure skips the interactive confirmation for scripted enrollment,
+		// but the connection is still pinned to whichever fingerprint is presented now.
+	default:
+		fmt.Printf("Server presented certificate fingerprint (SHA256): %s\n", fingerprint)
+		fmt.Printf("Verify this out-of-band (e.g. with the server's administrator) before trusting it.\n")
+		confirm := false
+		prompt := &survey.Confirm{Message: "Trust this fingerprint and continue enrollment?"}
+		survey.AskOne(prompt, &confirm)
+		if !confirm {
+			return fmt.Errorf("enrollment aborted: fingerprint not confirmed")
+		}
+	}
+
+	body, err := json.Marshal(enrollmentRequest{Token: token})
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify:    true,
+				VerifyPeerCertificate: pinFingerprint(fingerprint),
+			},
+		},
+	}
+
+	resp, err := client.Post(enrollURL+"/enroll", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("enrollment request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("enrollment rejected by server (status %d)", resp.StatusCode)
+	}
+
+	var config enrolledConfig
+	if err := json.NewDecoder(resp.Body).Decode(&config); err != nil {
+		return fmt.Errorf("malformed enrollment response: %w", err)
+	}
+
+	if save == "" {
+		save, err = os.Getwd()
+		if err != nil {
+			return err
+		}
+	}
+	saveTo, err := filepath.Abs(save)
+	if err != nil {
+		return err
+	}
+	if fi, err := os.Stat(saveTo); err == nil && fi.IsDir() {
+		saveTo = filepath.Join(saveTo, fmt.Sprintf("%s.cfg", config.Operator))
+	}
+
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(saveTo, configJSON, 0o600); err != nil {
+		return fmt.Errorf("failed to write config to %s: %w", saveTo, err)
+	}
+
+	fmt.Printf("Enrolled as %s, config saved to %s\n", config.Operator, saveTo)
+	return nil
+}
+
+// probeServerFingerprint - dial the enrollment endpoint without verifying its certificate
+// and return the leaf certificate's SHA256 fingerprint, for the operator to confirm out-of-band
+func probeServerFingerprint(rawURL string) (*x509.Certificate, string, error) {
+	host, err := enrollHost(rawURL)
+	if err != nil {
+		return nil, "", err
+	}
+	conn, err := tls.Dial("tcp", host, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to connect to %s for certificate pinning: %w", host, err)
+	}
+	defer conn.Close()
+	peerCerts := conn.ConnectionState().PeerCertificates
+	if len(peerCerts) == 0 {
+		return nil, "", fmt.Errorf("server presented no certificate")
+	}
+	leaf := peerCerts[0]
+	sum := sha256.Sum256(leaf.Raw)
+	return leaf, hex.EncodeToString(sum[:]), nil
+}
+
+// enrollHost - host:port to dial for TLS fingerprint probing, parsed from the enrollment URL
+func enrollHost(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid --url: %w", err)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("invalid --url: missing host")
+	}
+	if u.Port() == "" {
+		return u.Host + ":443", nil
+	}
+	return u.Host, nil
+}
+
+// pinFingerprint - tls.Config.VerifyPeerCertificate hook that rejects any certificate
+// other than the one whose fingerprint the operator already confirmed, so a MITM can't
+// swap certificates between the TOFU probe and the actual enrollment request
+func pinFingerprint(expected string) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return fmt.Errorf("server presented no certificate")
+		}
+		sum := sha256.Sum256(rawCerts[0])
+		if !fingerprintsEqual(hex.EncodeToString(sum[:]), expected) {
+			return fmt.Errorf("server certificate fingerprint changed since pinning")
+		}
+		return nil
+	}
+}
+
+// fingerprintsEqual - compare two hex-encoded fingerprints case-insensitively
+func fingerprintsEqual(a string, b string) bool {
+	return len(a) == len(b) && strings.EqualFold(a, b)
+}
@@ -32,27 +32,79 @@ import (
 	"github.com/bishopfox/sliver/client/console"
 	consts "github.com/bishopfox/sliver/client/constants"
 	"github.com/bishopfox/sliver/protobuf/sliverpb"
+	"github.com/bishopfox/sliver/server/assets"
 	"github.com/desertbit/grumble"
 )
 
 const (
 	defaultTimeout = 60
 
-	windowsDefaultHostProc = `c:\windows\system32\notepad.exe`
-	linuxDefaultHostProc   = "/bin/bash"
-	macosDefaultHostProc   = "/Applications/Safari.app/Contents/MacOS/SafariForWebKitDevelopment"
+	windowsDefaultHostProc    = `c:\windows\system32\notepad.exe`
+	windows32DefaultHostProc  = `C:\Windows\SysWOW64\notepad.exe`
+	linuxDefaultHostProc      = "/bin/bash"
+	macosDefaultHostProc      = "/Applications/Safari.app/Contents/MacOS/SafariForWebKitDevelopment"
+	macosArm64DefaultHostProc = "/System/Applications/Utilities/Terminal.app/Contents/MacOS/Terminal"
 )
 
 var commandMap map[string]macro
-var defaultHostProc = map[string]string{
-	"windows": windowsDefaultHostProc,
-	"linux":   windowsDefaultHostProc,
-	"darwin":  macosDefaultHostProc,
+
+// defaultHostProc - per-OS, per-arch default process to sideload/spawn into.
+// An OS/arch pair that isn't listed has no default; getDefaultProcess returns an error for it.
+var defaultHostProc = map[string]map[string]string{
+	"windows": {
+		"amd64": windowsDefaultHostProc,
+		"386":   windows32DefaultHostProc,
+		"arm64": windowsDefaultHostProc,
+	},
+	"linux": {
+		"amd64": linuxDefaultHostProc,
+		"arm64": linuxDefaultHostProc,
+	},
+	"darwin": {
+		"amd64": macosDefaultHostProc,
+		"arm64": macosArm64DefaultHostProc,
+	},
+}
+
+// canonicalArch - normalize the various arch spellings we see from sessions/beacons
+// and manifests (e.g. legacy "x64"/"x86") down to GOARCH-style names.
+func canonicalArch(arch string) string {
+	switch arch {
+	case "x64", "amd64", "x86_64":
+		return "amd64"
+	case "x86", "386", "i386":
+		return "386"
+	case "arm64", "aarch64":
+		return "arm64"
+	case "arm":
+		return "arm"
+	default:
+		return arch
+	}
 }
 
-type binFiles struct {
-	Ext64Path string `json:"x64"`
-	Ext32Path string `json:"x86"`
+// binFiles - payload paths for a macro, keyed by canonical arch (amd64, 386, arm64, arm).
+// Unmarshals the legacy {"x64": ..., "x86": ...} shape transparently for older manifests.
+type binFiles map[string]string
+
+func (b *binFiles) UnmarshalJSON(data []byte) error {
+	raw := map[string]string{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	out := make(binFiles, len(raw))
+	for key, value := range raw {
+		switch key {
+		case "x64":
+			out["amd64"] = value
+		case "x86":
+			out["386"] = value
+		default:
+			out[canonicalArch(key)] = value
+		}
+	}
+	*b = out
+	return nil
 }
 
 type macroFile struct {
@@ -72,10 +124,15 @@ type macroCommand struct {
 	IsAssembly   bool        `json:"IsAssembly"`
 }
 
-func (ec *macroCommand) getDefaultProcess(targetOS string) (proc string, err error) {
-	proc, ok := defaultHostProc[targetOS]
+func (ec *macroCommand) getDefaultProcess(targetOS string, targetArch string) (proc string, err error) {
+	byArch, ok := defaultHostProc[targetOS]
 	if !ok {
 		err = fmt.Errorf("no default process for %s target, please specify one", targetOS)
+		return
+	}
+	proc, ok = byArch[canonicalArch(targetArch)]
+	if !ok {
+		err = fmt.Errorf("no default process for %s/%s target, please specify one", targetOS, targetArch)
 	}
 	return
 }
@@ -84,30 +141,28 @@ type macro struct {
 	Name     string         `json:"macroName"`
 	Commands []macroCommand `json:"macroCommands"`
 	Path     string
+	SignedBy string // key ID that signed this macro's manifest, empty if unsigned
 }
 
 func (e *macro) getFileForTarget(cmdName string, targetOS string, targetArch string) (filePath string, err error) {
+	arch := canonicalArch(targetArch)
 	for _, c := range e.Commands {
-		if cmdName == c.Name {
-			for _, ef := range c.MacroFiles {
-				if targetOS == ef.OS {
-					switch targetArch {
-					case "x86":
-						filePath = filepath.Join(e.Path, ef.Files.Ext32Path)
-					case "x64":
-						filePath = filepath.Join(e.Path, ef.Files.Ext64Path)
-					default:
-						filePath = filepath.Join(e.Path, ef.Files.Ext64Path)
-					}
-				}
+		if cmdName != c.Name {
+			continue
+		}
+		for _, ef := range c.MacroFiles {
+			if targetOS != ef.OS {
+				continue
 			}
-
+			path, ok := ef.Files[arch]
+			if !ok {
+				err = fmt.Errorf("macro %q has no payload for %s/%s, refusing to sideload an incompatible binary", cmdName, targetOS, targetArch)
+				return "", err
+			}
+			return filepath.Join(e.Path, path), nil
 		}
 	}
-	if filePath == "" {
-		err = fmt.Errorf("no macro file found for %s/%s", targetOS, targetArch)
-	}
-	return
+	return "", fmt.Errorf("no macro file found for %s/%s", targetOS, targetArch)
 }
 
 func (e *macro) getCommandFromName(name string) (extCmd *macroCommand, err error) {
@@ -121,6 +176,25 @@ func (e *macro) getCommandFromName(name string) (extCmd *macroCommand, err error
 	return
 }
 
+// LoadMacroCommand - the `load` command itself, for the console to App.AddCommand
+func LoadMacroCommand(con *console.SliverConsoleClient) *grumble.Command {
+	return &grumble.Command{
+		Name: "load",
+		Help: "Load a macro into the Sliver shell",
+		Run: func(ctx *grumble.Context) error {
+			LoadMacroCmd(ctx, con)
+			return nil
+		},
+		Flags: func(f *grumble.Flags) {
+			f.Bool("", "allow-unsigned", false, "load the macro even if its manifest signature is missing or invalid")
+		},
+		Args: func(a *grumble.Args) {
+			a.String("dir-path", "path to the macro's directory (containing manifest.json)")
+		},
+		HelpGroup: consts.MacroHelpGroup,
+	}
+}
+
 // LoadMacroCmd - Locally load a macro into the Sliver shell.
 func LoadMacroCmd(ctx *grumble.Context, con *console.SliverConsoleClient) {
 
@@ -140,6 +214,20 @@ func LoadMacroCmd(ctx *grumble.Context, con *console.SliverConsoleClient) {
 		return
 	}
 	ext.Path = dirPath
+
+	allowUnsigned := ctx.Flags.Bool("allow-unsigned")
+	keyID, err := verifyMacroSignature(assets.GetRootAppDir(), dirPath, jsonBytes, ext)
+	if err != nil {
+		if !allowUnsigned {
+			con.PrintErrorf("Refusing to load unsigned/unverified macro: %s (pass --allow-unsigned to override)\n", err)
+			return
+		}
+		con.PrintWarnf("Loading unsigned macro: %s\n", err)
+	} else {
+		ext.SignedBy = keyID
+		con.PrintInfof("Manifest signature verified (key %s)\n", keyID)
+	}
+
 	// for each macro command, add a new app command
 	for _, extCmd := range ext.Commands {
 		// do not add if the command already exists
@@ -214,10 +302,14 @@ func runMacroCommand(ctx *grumble.Context, con *console.SliverConsoleClient) {
 	} else {
 		extArgs = strings.Join(args, " ")
 	}
+	execVerb := fmt.Sprintf("Executing %s %s ...", ctx.Command.Name, extArgs)
+	if ext.SignedBy != "" {
+		execVerb = fmt.Sprintf("Executing signed macro (key %s): %s %s ...", ext.SignedBy, ctx.Command.Name, extArgs)
+	}
 	entryPoint := c.Entrypoint
 	processName := ctx.Flags.String("process")
 	if processName == "" {
-		processName, err = c.getDefaultProcess(session.GetOS())
+		processName, err = c.getDefaultProcess(session.GetOS(), session.GetArch())
 		if err != nil {
 			con.PrintErrorf("%s\n", err)
 			return
@@ -243,8 +335,7 @@ func runMacroCommand(ctx *grumble.Context, con *console.SliverConsoleClient) {
 	}
 	if c.IsAssembly {
 		ctrl := make(chan bool)
-		msg := fmt.Sprintf("Executing %s %s ...", ctx.Command.Name, extArgs)
-		con.SpinUntil(msg, ctrl)
+		con.SpinUntil(execVerb, ctrl)
 		executeAssemblyResp, err := con.Rpc.ExecuteAssembly(context.Background(), &sliverpb.ExecuteAssemblyReq{
 			Request:   con.ActiveTarget.Request(ctx),
 			IsDLL:     isDLL,
@@ -269,8 +360,7 @@ func runMacroCommand(ctx *grumble.Context, con *console.SliverConsoleClient) {
 		}
 	} else if c.IsReflective {
 		ctrl := make(chan bool)
-		msg := fmt.Sprintf("Executing %s %s ...", ctx.Command.Name, extArgs)
-		con.SpinUntil(msg, ctrl)
+		con.SpinUntil(execVerb, ctrl)
 		spawnDllResp, err := con.Rpc.SpawnDll(context.Background(), &sliverpb.InvokeSpawnDllReq{
 			Request:     con.ActiveTarget.Request(ctx),
 			Args:        strings.Trim(extArgs, " "),
@@ -294,8 +384,7 @@ func runMacroCommand(ctx *grumble.Context, con *console.SliverConsoleClient) {
 		}
 	} else {
 		ctrl := make(chan bool)
-		msg := fmt.Sprintf("Executing %s %s ...", ctx.Command.Name, extArgs)
-		con.SpinUntil(msg, ctrl)
+		con.SpinUntil(execVerb, ctrl)
 		sideloadResp, err := con.Rpc.Sideload(context.Background(), &sliverpb.SideloadReq{
 			Request:     con.ActiveTarget.Request(ctx),
 			Args:        extArgs,
@@ -0,0 +1,203 @@
+package macros
+
+/*
+	Sliver Implant Framework
+	Copyright (C) 2019  Bishop Fox
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	manifestSigExt  = ".minisig"
+	trustedKeysDir  = "trusted_keys"
+	minisigAlgoEd   = "Ed"
+	minisigKeyIDLen = 8
+)
+
+// trustedKey - an Ed25519 public key the server trusts to sign macro manifests,
+// minisign-compatible so operators can reuse existing signing keys.
+type trustedKey struct {
+	ID        string // 8-byte key id, hex encoded
+	PublicKey ed25519.PublicKey
+}
+
+// loadTrustedKeys - read every minisign *.pub key under <AppDir>/macros/trusted_keys/
+func loadTrustedKeys(appDir string) (map[string]trustedKey, error) {
+	keys := map[string]trustedKey{}
+	dir := filepath.Join(appDir, "macros", trustedKeysDir)
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return keys, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".pub") {
+			continue
+		}
+		raw, err := ioutil.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		key, err := parseMinisignPublicKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		keys[key.ID] = key
+	}
+	return keys, nil
+}
+
+// parseMinisignPublicKey - minisign pub key files are two lines: an untrusted
+// comment, then base64(sig_algo(2) || key_id(8) || public_key(32))
+func parseMinisignPublicKey(raw []byte) (trustedKey, error) {
+	lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+	if len(lines) < 2 {
+		return trustedKey{}, fmt.Errorf("malformed minisign public key")
+	}
+	blob, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil {
+		return trustedKey{}, fmt.Errorf("decoding public key: %w", err)
+	}
+	if len(blob) != 2+minisigKeyIDLen+ed25519.PublicKeySize {
+		return trustedKey{}, fmt.Errorf("unexpected public key length %d", len(blob))
+	}
+	if string(blob[:2]) != minisigAlgoEd {
+		return trustedKey{}, fmt.Errorf("unsupported signature algorithm %q", blob[:2])
+	}
+	return trustedKey{
+		ID:        hex.EncodeToString(blob[2 : 2+minisigKeyIDLen]),
+		PublicKey: ed25519.PublicKey(blob[2+minisigKeyIDLen:]),
+	}, nil
+}
+
+// minisignSignature - a parsed manifest.json.minisig detached signature
+type minisignSignature struct {
+	KeyID     string
+	Signature []byte
+}
+
+// parseMinisignSignature - minisign .minisig files are: an untrusted comment
+// line, base64(sig_algo(2) || key_id(8) || signature(64)), a trusted comment
+// line, and a base64 global signature we don't need here.
+func parseMinisignSignature(raw []byte) (minisignSignature, error) {
+	lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+	if len(lines) < 2 {
+		return minisignSignature{}, fmt.Errorf("malformed minisig file")
+	}
+	blob, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil {
+		return minisignSignature{}, fmt.Errorf("decoding signature: %w", err)
+	}
+	if len(blob) != 2+minisigKeyIDLen+ed25519.SignatureSize {
+		return minisignSignature{}, fmt.Errorf("unexpected signature length %d", len(blob))
+	}
+	if string(blob[:2]) != minisigAlgoEd {
+		return minisignSignature{}, fmt.Errorf("unsupported signature algorithm %q", blob[:2])
+	}
+	return minisignSignature{
+		KeyID:     hex.EncodeToString(blob[2 : 2+minisigKeyIDLen]),
+		Signature: blob[2+minisigKeyIDLen:],
+	}, nil
+}
+
+// macroFileManifest - builds the canonical sha256sum-style listing that gets
+// signed: the manifest itself plus every binary every command references,
+// sorted so the listing (and thus the signature) is order-independent.
+func macroFileManifest(dirPath string, manifestBytes []byte, ext *macro) ([]byte, error) {
+	hashes := map[string]string{
+		"manifest.json": hashBytes(manifestBytes),
+	}
+	for _, cmd := range ext.Commands {
+		for _, mf := range cmd.MacroFiles {
+			for _, relPath := range mf.Files {
+				if _, ok := hashes[relPath]; ok {
+					continue
+				}
+				data, err := ioutil.ReadFile(filepath.Join(dirPath, relPath))
+				if err != nil {
+					return nil, fmt.Errorf("hashing %s: %w", relPath, err)
+				}
+				hashes[relPath] = hashBytes(data)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(hashes))
+	for name := range hashes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var listing strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&listing, "%s  %s\n", hashes[name], name)
+	}
+	return []byte(listing.String()), nil
+}
+
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// verifyMacroSignature - verify dirPath/manifest.json.minisig covers manifestBytes
+// and every binary ext references, against the server's trusted key set.
+// Returns the signing key's ID on success.
+func verifyMacroSignature(appDir string, dirPath string, manifestBytes []byte, ext *macro) (string, error) {
+	sigPath := filepath.Join(dirPath, "manifest.json"+manifestSigExt)
+	sigBytes, err := ioutil.ReadFile(sigPath)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("no signature found at %s", sigPath)
+	}
+	if err != nil {
+		return "", err
+	}
+	sig, err := parseMinisignSignature(sigBytes)
+	if err != nil {
+		return "", err
+	}
+
+	trustedKeys, err := loadTrustedKeys(appDir)
+	if err != nil {
+		return "", err
+	}
+	key, ok := trustedKeys[sig.KeyID]
+	if !ok {
+		return "", fmt.Errorf("manifest signed by untrusted key %s", sig.KeyID)
+	}
+
+	listing, err := macroFileManifest(dirPath, manifestBytes, ext)
+	if err != nil {
+		return "", err
+	}
+	if !ed25519.Verify(key.PublicKey, listing, sig.Signature) {
+		return "", fmt.Errorf("signature verification failed for key %s", sig.KeyID)
+	}
+	return sig.KeyID, nil
+}
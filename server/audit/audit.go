@@ -0,0 +1,205 @@
+package audit
+
+/*
+	Sliver Implant Framework
+	Copyright (C) 2019  Bishop Fox
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Record - one structured entry in the operator action log
+type Record struct {
+	Timestamp time.Time              `json:"ts"`
+	Operator  string                 `json:"operator"`
+	Action    string                 `json:"action"`
+	Args      map[string]interface{} `json:"args,omitempty"`
+	Result    string                 `json:"result,omitempty"`
+}
+
+// SignedTreeHead - CT-style STH: commits to the full audit log at a point in time
+type SignedTreeHead struct {
+	Size      int       `json:"size"`
+	RootHash  string    `json:"root_hash"`
+	Timestamp time.Time `json:"ts"`
+	Signature string    `json:"signature,omitempty"`
+}
+
+// Log - an append-only, Merkle-tree-backed operator action log
+type Log struct {
+	mu     sync.Mutex
+	file   *os.File
+	tree   compactTree
+	leaves [][32]byte
+	sthDir string
+}
+
+// Open - open (creating if necessary) the audit log file under <appDir>/audit/
+func Open(appDir string) (*Log, error) {
+	dir := filepath.Join(appDir, "audit")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	logPath := filepath.Join(dir, "audit.log")
+	file, err := os.OpenFile(logPath, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	log := &Log{file: file, sthDir: dir}
+	if err := log.replay(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return log, nil
+}
+
+// replay - rebuild the in-memory tree from whatever records are already on disk,
+// so a server restart doesn't lose the audit history's Merkle commitment
+func (l *Log) replay() error {
+	if _, err := l.file.Seek(0, 0); err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(l.file)
+	for decoder.More() {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return fmt.Errorf("corrupt audit log: %w", err)
+		}
+		leaf := leafHash(raw)
+		l.leaves = append(l.leaves, leaf)
+		l.tree.Append(leaf)
+	}
+	if _, err := l.file.Seek(0, 2); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Write - append a record to the log and fold it into the Merkle tree
+func (l *Log) Write(record Record) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := l.file.Write(append(raw, '\n')); err != nil {
+		return err
+	}
+	leaf := leafHash(raw)
+	l.leaves = append(l.leaves, leaf)
+	l.tree.Append(leaf)
+	return nil
+}
+
+// Head - the current (unsigned) tree head
+func (l *Log) Head() (SignedTreeHead, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	root, err := l.tree.Root()
+	if err != nil {
+		return SignedTreeHead{}, err
+	}
+	return SignedTreeHead{
+		Size:      l.tree.size,
+		RootHash:  hex.EncodeToString(root[:]),
+		Timestamp: time.Now(),
+	}, nil
+}
+
+// InclusionProof - an audit path proving the record at index is part of the
+// tree as of head (which must be <= the log's current size)
+func (l *Log) InclusionProof(index int, head int) ([]string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if head > len(l.leaves) {
+		return nil, fmt.Errorf("head %d is larger than the log (%d records)", head, len(l.leaves))
+	}
+	proof, err := inclusionProof(l.leaves, index, head)
+	if err != nil {
+		return nil, err
+	}
+	hexProof := make([]string, len(proof))
+	for i, node := range proof {
+		hexProof[i] = hex.EncodeToString(node[:])
+	}
+	return hexProof, nil
+}
+
+// signFunc - callback type so this package doesn't need to depend on the CA's key format
+type signFunc func(data []byte) (string, error)
+
+// PublishSignedHead - sign the current tree head with sign and persist it to
+// <appDir>/audit/sth.json so operators can fetch the last-published commitment
+func (l *Log) PublishSignedHead(sign signFunc) (SignedTreeHead, error) {
+	sth, err := l.Head()
+	if err != nil {
+		return SignedTreeHead{}, err
+	}
+	digest := fmt.Sprintf("%d|%s|%d", sth.Size, sth.RootHash, sth.Timestamp.Unix())
+	sig, err := sign([]byte(digest))
+	if err != nil {
+		return SignedTreeHead{}, err
+	}
+	sth.Signature = sig
+
+	data, err := json.MarshalIndent(sth, "", "  ")
+	if err != nil {
+		return SignedTreeHead{}, err
+	}
+	if err := os.WriteFile(filepath.Join(l.sthDir, "sth.json"), data, 0600); err != nil {
+		return SignedTreeHead{}, err
+	}
+	return sth, nil
+}
+
+// StartPeriodicPublish - call PublishSignedHead every interval until the returned stop func
+// is called, mirroring core.StartEnrollmentSweepLoop's ticker pattern. Without this an STH
+// only ever gets published when an operator happens to run `audit head`, so a server that
+// just sits there never commits to a tamper-evident tree head at all. Publish errors are
+// handed to onError rather than returned, since this runs unattended in the background.
+func (l *Log) StartPeriodicPublish(interval time.Duration, sign signFunc, onError func(err error)) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := l.PublishSignedHead(sign); err != nil && onError != nil {
+					onError(err)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// Close - close the underlying log file
+func (l *Log) Close() error {
+	return l.file.Close()
+}
@@ -0,0 +1,158 @@
+package audit
+
+/*
+	Sliver Implant Framework
+	Copyright (C) 2019  Bishop Fox
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"crypto/sha256"
+	"fmt"
+)
+
+// leafHash/interiorHash follow RFC 6962 §2.1: leaves and interior nodes are
+// domain-separated so a leaf can never be mistaken for an interior node.
+func leafHash(record []byte) [32]byte {
+	return sha256.Sum256(append([]byte{0x00}, record...))
+}
+
+func interiorHash(left [32]byte, right [32]byte) [32]byte {
+	buf := make([]byte, 0, 1+len(left)+len(right))
+	buf = append(buf, 0x01)
+	buf = append(buf, left[:]...)
+	buf = append(buf, right[:]...)
+	return sha256.Sum256(buf)
+}
+
+// frontierNode - one peak on the tree's right edge: the root of a perfect
+// subtree of size 2^level that hasn't yet been merged into a bigger subtree.
+type frontierNode struct {
+	level int
+	hash  [32]byte
+}
+
+// compactTree - maintains only the O(log n) peaks needed to append a leaf
+// and recompute the current root in O(log n), instead of keeping the whole tree.
+type compactTree struct {
+	size  int
+	peaks []frontierNode
+}
+
+// Append - add a new leaf, merging peaks of equal level just like a binary counter increment
+func (t *compactTree) Append(leaf [32]byte) {
+	node := frontierNode{level: 0, hash: leaf}
+	for len(t.peaks) > 0 && t.peaks[len(t.peaks)-1].level == node.level {
+		top := t.peaks[len(t.peaks)-1]
+		t.peaks = t.peaks[:len(t.peaks)-1]
+		node = frontierNode{level: node.level + 1, hash: interiorHash(top.hash, node.hash)}
+	}
+	t.peaks = append(t.peaks, node)
+	t.size++
+}
+
+// Root - RFC 6962 MTH(D[0:size]), folded right-to-left across the peak stack
+func (t *compactTree) Root() ([32]byte, error) {
+	if t.size == 0 {
+		return [32]byte{}, fmt.Errorf("empty tree has no root")
+	}
+	root := t.peaks[len(t.peaks)-1].hash
+	for i := len(t.peaks) - 2; i >= 0; i-- {
+		root = interiorHash(t.peaks[i].hash, root)
+	}
+	return root, nil
+}
+
+// largestPowerOfTwoLessThan - RFC 6962's split point k: the largest power of
+// two strictly smaller than n
+func largestPowerOfTwoLessThan(n int) int {
+	k := 1
+	for k < n {
+		k <<= 1
+	}
+	return k >> 1
+}
+
+// subtreeRoot - MTH(D[lo:hi]) recomputed directly from leaf hashes
+func subtreeRoot(leaves [][32]byte, lo int, hi int) [32]byte {
+	if hi-lo == 1 {
+		return leaves[lo]
+	}
+	k := largestPowerOfTwoLessThan(hi - lo)
+	left := subtreeRoot(leaves, lo, lo+k)
+	right := subtreeRoot(leaves, lo+k, hi)
+	return interiorHash(left, right)
+}
+
+// inclusionProof - RFC 6962 §2.1.1 PATH(m, D[0:n]): the audit path proving
+// leaf index `index` is included in the tree formed by the first `size` leaves.
+func inclusionProof(leaves [][32]byte, index int, size int) ([][32]byte, error) {
+	if index < 0 || index >= size || size > len(leaves) {
+		return nil, fmt.Errorf("index %d out of range for tree of size %d", index, size)
+	}
+	return inclusionProofRec(leaves, index, 0, size), nil
+}
+
+func inclusionProofRec(leaves [][32]byte, index int, start int, size int) [][32]byte {
+	if size == 1 {
+		return [][32]byte{}
+	}
+	k := largestPowerOfTwoLessThan(size)
+	if index < k {
+		path := inclusionProofRec(leaves, index, start, k)
+		sibling := subtreeRoot(leaves, start+k, start+size)
+		return append(path, sibling)
+	}
+	path := inclusionProofRec(leaves, index-k, start+k, size-k)
+	sibling := subtreeRoot(leaves, start, start+k)
+	return append(path, sibling)
+}
+
+// verifyInclusion - recompute the root implied by a leaf + audit path and compare
+// against the published root, per RFC 6962 §2.1.3.1
+func verifyInclusion(leaf [32]byte, index int, size int, proof [][32]byte, root [32]byte) bool {
+	computed, err := verifyInclusionRec(leaf, index, 0, size, proof)
+	if err != nil {
+		return false
+	}
+	return computed == root
+}
+
+func verifyInclusionRec(node [32]byte, index int, start int, size int, proof [][32]byte) ([32]byte, error) {
+	if size == 1 {
+		if len(proof) != 0 {
+			return [32]byte{}, fmt.Errorf("unexpected remaining proof elements")
+		}
+		return node, nil
+	}
+	if len(proof) == 0 {
+		return [32]byte{}, fmt.Errorf("proof too short")
+	}
+	k := largestPowerOfTwoLessThan(size)
+	sibling := proof[len(proof)-1]
+	rest := proof[:len(proof)-1]
+	if index < k {
+		left, err := verifyInclusionRec(node, index, start, k, rest)
+		if err != nil {
+			return [32]byte{}, err
+		}
+		return interiorHash(left, sibling), nil
+	}
+	right, err := verifyInclusionRec(node, index-k, start+k, size-k, rest)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return interiorHash(sibling, right), nil
+}
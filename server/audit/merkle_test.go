@@ -0,0 +1,54 @@
+package audit
+
+/*
+	Sliver Implant Framework
+	Copyright (C) 2019  Bishop Fox
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import "testing"
+
+func TestLargestPowerOfTwoLessThan(t *testing.T) {
+	expected := map[int]int{
+		2: 1, 3: 2, 4: 2, 5: 4, 6: 4, 7: 4, 8: 4, 9: 8, 16: 8, 17: 16,
+	}
+	for n, want := range expected {
+		if got := largestPowerOfTwoLessThan(n); got != want {
+			t.Errorf("largestPowerOfTwoLessThan(%d) = %d, want %d", n, got, want)
+		}
+	}
+}
+
+// TestInclusionProofPowerOfTwoSizes guards against a split-point bug where
+// power-of-two tree sizes fed k=0 into subtreeRoot/inclusionProofRec and
+// recursed on an unchanged range forever.
+func TestInclusionProofPowerOfTwoSizes(t *testing.T) {
+	for size := 1; size <= 16; size++ {
+		leaves := make([][32]byte, size)
+		for i := range leaves {
+			leaves[i] = leafHash([]byte{byte(i)})
+		}
+		root := subtreeRoot(leaves, 0, size)
+		for index := 0; index < size; index++ {
+			proof, err := inclusionProof(leaves, index, size)
+			if err != nil {
+				t.Fatalf("size=%d index=%d: %v", size, index, err)
+			}
+			if !verifyInclusion(leaves[index], index, size, proof, root) {
+				t.Errorf("size=%d index=%d: inclusion proof did not verify", size, index)
+			}
+		}
+	}
+}
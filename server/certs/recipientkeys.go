@@ -0,0 +1,81 @@
+package certs
+
+/*
+	Sliver Implant Framework
+	Copyright (C) 2019  Bishop Fox
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/bishopfox/sliver/server/state"
+)
+
+const recipientKeyPrefix = "recipient-key/"
+
+var (
+	recipientKeyStoreMu sync.Mutex
+	recipientKeyStore   state.Store
+)
+
+func getRecipientKeyStore(rootDir string) (state.Store, error) {
+	recipientKeyStoreMu.Lock()
+	defer recipientKeyStoreMu.Unlock()
+	if recipientKeyStore == nil {
+		dbPath := filepath.Join(rootDir, ClientsCertDir, "recipient-keys.db")
+		store, err := state.NewBoltStore(dbPath)
+		if err != nil {
+			return nil, err
+		}
+		recipientKeyStore = store
+	}
+	return recipientKeyStore, nil
+}
+
+// RegisterOperatorRecipientKey - associate operator with an X25519 public key, so `new-player
+// --encrypt --recipient` can wrap their ClientConfig for that key instead of a passphrase
+func RegisterOperatorRecipientKey(rootDir string, operator string, publicKey [32]byte) error {
+	store, err := getRecipientKeyStore(rootDir)
+	if err != nil {
+		return err
+	}
+	return store.Put(recipientKeyPrefix+operator, []byte(hex.EncodeToString(publicKey[:])))
+}
+
+// GetOperatorRecipientKey - look up the X25519 public key registered for operator
+func GetOperatorRecipientKey(rootDir string, operator string) ([32]byte, error) {
+	var publicKey [32]byte
+	store, err := getRecipientKeyStore(rootDir)
+	if err != nil {
+		return publicKey, err
+	}
+	raw, err := store.Get(recipientKeyPrefix + operator)
+	if err == state.ErrNotFound {
+		return publicKey, fmt.Errorf("no recipient key registered for operator %s", operator)
+	}
+	if err != nil {
+		return publicKey, err
+	}
+	decoded, err := hex.DecodeString(string(raw))
+	if err != nil || len(decoded) != len(publicKey) {
+		return publicKey, fmt.Errorf("corrupt recipient key stored for operator %s", operator)
+	}
+	copy(publicKey[:], decoded)
+	return publicKey, nil
+}
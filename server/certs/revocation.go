@@ -0,0 +1,268 @@
+package certs
+
+/*
+	Sliver Implant Framework
+	Copyright (C) 2019  Bishop Fox
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bishopfox/sliver/server/state"
+)
+
+const revocationListKey = "revoked-operators"
+
+// RevokedCert - one entry in the client certificate revocation list
+type RevokedCert struct {
+	Serial    string    `json:"serial"`
+	Operator  string    `json:"operator"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+var (
+	// revocationListMu - serializes the load-modify-save sequence in RevokeCertificate/
+	// UnrevokeCertificate; distinct from revocationStoreMu since getRevocationStore is
+	// called while it's held and the two must not share a lock.
+	revocationListMu sync.Mutex
+
+	revocationStoreMu sync.Mutex
+	revocationStore   state.Store
+)
+
+// SetRevocationStore - point the revocation list at a shared Store, the same way
+// core.SetEnrollmentStore does for enrollment tokens, so `kick-player` on one
+// cluster node is immediately enforced by transport.StartClientListener on every
+// other node. Defaults to a process-local BoltStore if never called.
+func SetRevocationStore(store state.Store) {
+	revocationStoreMu.Lock()
+	defer revocationStoreMu.Unlock()
+	revocationStore = store
+}
+
+func getRevocationStore(rootDir string) (state.Store, error) {
+	revocationStoreMu.Lock()
+	defer revocationStoreMu.Unlock()
+	if revocationStore == nil {
+		dbPath := filepath.Join(rootDir, ClientsCertDir, "revocation.db")
+		store, err := state.NewBoltStore(dbPath)
+		if err != nil {
+			return nil, err
+		}
+		revocationStore = store
+	}
+	return revocationStore, nil
+}
+
+// LoadRevocationList - read every revoked client certificate serial for this server
+func LoadRevocationList(rootDir string) ([]RevokedCert, error) {
+	revocationListMu.Lock()
+	defer revocationListMu.Unlock()
+	return loadRevocationListLocked(rootDir)
+}
+
+func loadRevocationListLocked(rootDir string) ([]RevokedCert, error) {
+	store, err := getRevocationStore(rootDir)
+	if err != nil {
+		return nil, err
+	}
+	data, err := store.Get(revocationListKey)
+	if err == state.ErrNotFound {
+		return []RevokedCert{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	revoked := []RevokedCert{}
+	if err := json.Unmarshal(data, &revoked); err != nil {
+		return nil, err
+	}
+	return revoked, nil
+}
+
+func saveRevocationListLocked(rootDir string, revoked []RevokedCert) error {
+	store, err := getRevocationStore(rootDir)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(revoked, "", "  ")
+	if err != nil {
+		return err
+	}
+	return store.Put(revocationListKey, data)
+}
+
+// RevokeCertificate - add a client certificate serial to the persistent revocation list
+func RevokeCertificate(rootDir string, serial string, operator string) error {
+	revocationListMu.Lock()
+	defer revocationListMu.Unlock()
+	revoked, err := loadRevocationListLocked(rootDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range revoked {
+		if entry.Serial == serial {
+			return nil // already revoked
+		}
+	}
+	revoked = append(revoked, RevokedCert{
+		Serial:    serial,
+		Operator:  operator,
+		RevokedAt: time.Now(),
+	})
+	return saveRevocationListLocked(rootDir, revoked)
+}
+
+// UnrevokeCertificate - remove a serial from the revocation list, allowing a re-enrolled operator back in
+func UnrevokeCertificate(rootDir string, serial string) error {
+	revocationListMu.Lock()
+	defer revocationListMu.Unlock()
+	revoked, err := loadRevocationListLocked(rootDir)
+	if err != nil {
+		return err
+	}
+	kept := revoked[:0]
+	for _, entry := range revoked {
+		if entry.Serial != serial {
+			kept = append(kept, entry)
+		}
+	}
+	return saveRevocationListLocked(rootDir, kept)
+}
+
+// IsRevoked - check whether serial is on the revocation list; intended to be called
+// from transport.tls.Config.VerifyPeerCertificate on every client handshake
+func IsRevoked(rootDir string, serial string) bool {
+	revoked, err := LoadRevocationList(rootDir)
+	if err != nil {
+		return false
+	}
+	for _, entry := range revoked {
+		if entry.Serial == serial {
+			return true
+		}
+	}
+	return false
+}
+
+// LookupClientCertificateSerial - find the serial of the client certificate issued to
+// operator, regardless of whether that operator currently has a live connection. Used by
+// `kick-player` so an operator can be revoked while offline instead of only when connected.
+func LookupClientCertificateSerial(rootDir string, operator string) (string, error) {
+	dir := filepath.Join(rootDir, ClientsCertDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read client certificate directory: %w", err)
+	}
+
+	var newest *x509.Certificate
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		block, _ := pem.Decode(data)
+		if block == nil || block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil || cert.Subject.CommonName != operator {
+			continue
+		}
+		if newest == nil || cert.NotBefore.After(newest.NotBefore) {
+			newest = cert
+		}
+	}
+	if newest == nil {
+		return "", fmt.Errorf("no client certificate found for operator %s", operator)
+	}
+	return newest.SerialNumber.String(), nil
+}
+
+// crlSigningContext - domain-separation prefix for CRL signatures, so a signature produced
+// here can never be replayed as a valid signed tree head (see console.sthSigningContext)
+// even though both are signed with the same clients CA key.
+const crlSigningContext = "sliver-crl-v1|"
+
+// GenerateSignedCRL - render the revocation list as JSON and sign it with the
+// clients CA's private key, so a re-enrolling operator can verify it wasn't
+// forged by a man-in-the-middle on the distribution channel.
+func GenerateSignedCRL(rootDir string) ([]byte, []byte, error) {
+	revoked, err := LoadRevocationList(rootDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := json.Marshal(revoked)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sig, err := SignWithClientsCAKey(rootDir, append([]byte(crlSigningContext), body...))
+	if err != nil {
+		return nil, nil, err
+	}
+	return body, sig, nil
+}
+
+// SignWithClientsCAKey - sign an arbitrary digest with the clients CA's private key;
+// shared by GenerateSignedCRL and the audit log's signed tree heads so both trust
+// the same root of authority
+func SignWithClientsCAKey(rootDir string, data []byte) ([]byte, error) {
+	_, caKeyPEM, err := GetCertificateAuthorityPEM(rootDir, ClientsCertDir)
+	if err != nil {
+		return nil, err
+	}
+	caKey, err := parseRSAPrivateKeyPEM(caKeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("parsing CA key: %w", err)
+	}
+
+	digest := sha256.Sum256(data)
+	return rsa.SignPKCS1v15(rand.Reader, caKey, crypto.SHA256, digest[:])
+}
+
+func parseRSAPrivateKeyPEM(keyPEM []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode PEM block")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("CA key is not RSA")
+	}
+	return rsaKey, nil
+}
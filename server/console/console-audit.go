@@ -0,0 +1,136 @@
+package console
+
+/*
+	Sliver Implant Framework
+	Copyright (C) 2019  Bishop Fox
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bishopfox/sliver/server/assets"
+	"github.com/bishopfox/sliver/server/audit"
+	"github.com/bishopfox/sliver/server/certs"
+
+	"github.com/desertbit/grumble"
+)
+
+var (
+	auditLogOnce sync.Once
+	auditLog     *audit.Log
+)
+
+// getAuditLog - lazily open the shared, Merkle-tree-backed operator action log
+func getAuditLog() *audit.Log {
+	auditLogOnce.Do(func() {
+		log, err := audit.Open(assets.GetRootAppDir())
+		if err != nil {
+			fmt.Printf(Warn+"Failed to open audit log: %v\n", err)
+			return
+		}
+		auditLog = log
+	})
+	return auditLog
+}
+
+// writeAuditRecord - append a structured record of an operator-facing command to the
+// audit log; failures are logged but never block the command they're recording
+func writeAuditRecord(operator string, action string, args map[string]interface{}, result string) {
+	log := getAuditLog()
+	if log == nil {
+		return
+	}
+	err := log.Write(audit.Record{
+		Timestamp: time.Now(),
+		Operator:  operator,
+		Action:    action,
+		Args:      args,
+		Result:    result,
+	})
+	if err != nil {
+		fmt.Printf(Warn+"Failed to write audit record: %v\n", err)
+	}
+}
+
+// sthSigningContext - domain-separation prefix for STH signatures, so a signature produced
+// here can never be replayed as a valid CRL signature (see certs.GenerateSignedCRL's own
+// "sliver-crl-v1|" prefix) even though both are signed with the same clients CA key.
+const sthSigningContext = "sliver-sth-v1|"
+
+// signTreeHead - audit.signFunc that signs an STH digest with the clients CA key
+func signTreeHead(rootDir string, data []byte) (string, error) {
+	sig, err := certs.SignWithClientsCAKey(rootDir, append([]byte(sthSigningContext), data...))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+// startAuditPublishLoop - publish a signed tree head every interval for as long as the
+// server runs, so tampering is detectable even if no operator ever runs `audit head`
+func startAuditPublishLoop(interval time.Duration) (stop func()) {
+	log := getAuditLog()
+	if log == nil {
+		return func() {}
+	}
+	rootDir := assets.GetRootAppDir()
+	return log.StartPeriodicPublish(interval, func(data []byte) (string, error) {
+		return signTreeHead(rootDir, data)
+	}, func(err error) {
+		fmt.Printf(Warn+"Failed to publish signed tree head: %v\n", err)
+	})
+}
+
+// auditHeadCmd - sign and print the audit log's current tree head
+func auditHeadCmd(ctx *grumble.Context) {
+	log := getAuditLog()
+	if log == nil {
+		fmt.Printf(Warn + "Audit log is not available\n")
+		return
+	}
+	rootDir := assets.GetRootAppDir()
+	sth, err := log.PublishSignedHead(func(data []byte) (string, error) {
+		return signTreeHead(rootDir, data)
+	})
+	if err != nil {
+		fmt.Printf(Warn+"Failed to publish signed tree head: %v\n", err)
+		return
+	}
+	fmt.Printf(Info+"Signed tree head: size=%d root=%s\n", sth.Size, sth.RootHash)
+}
+
+// auditProveCmd - print the audit path proving a record's inclusion in a past tree head
+func auditProveCmd(ctx *grumble.Context) {
+	index := ctx.Flags.Int("index")
+	head := ctx.Flags.Int("head")
+
+	log := getAuditLog()
+	if log == nil {
+		fmt.Printf(Warn + "Audit log is not available\n")
+		return
+	}
+	proof, err := log.InclusionProof(index, head)
+	if err != nil {
+		fmt.Printf(Warn+"Failed to build inclusion proof: %v\n", err)
+		return
+	}
+	proofJSON, _ := json.MarshalIndent(proof, "", "  ")
+	fmt.Printf(Info+"Inclusion proof for record %d as of head %d:\n%s\n", index, head, proofJSON)
+}
@@ -0,0 +1,57 @@
+package console
+
+/*
+	Sliver Implant Framework
+	Copyright (C) 2019  Bishop Fox
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/bishopfox/sliver/server/assets"
+	"github.com/bishopfox/sliver/server/certs"
+)
+
+// signedCRLResponse - the revoked-serial listing plus a CA signature over it,
+// so a re-enrolling operator can detect if the distribution channel tampered with it.
+type signedCRLResponse struct {
+	Revoked   json.RawMessage `json:"revoked"`
+	Signature string          `json:"signature"`
+}
+
+// crlHandler - serve the signed client certificate revocation list
+func crlHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, sig, err := certs.GenerateSignedCRL(assets.GetRootAppDir())
+	if err != nil {
+		log.Printf("failed to generate CRL: %v", err)
+		http.Error(w, "failed to generate CRL", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(signedCRLResponse{
+		Revoked:   body,
+		Signature: base64.StdEncoding.EncodeToString(sig),
+	})
+}
@@ -0,0 +1,254 @@
+package console
+
+/*
+	Sliver Implant Framework
+	Copyright (C) 2019  Bishop Fox
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/term"
+)
+
+// Encrypted ClientConfig container layout. The byte after the magic selects the KDF:
+//
+//	magic (4 bytes)              "SCFG"
+//	version (1 byte)             1 = passphrase (argon2id), 2 = recipient key (X25519)
+//
+// version 1 (passphrase) continues:
+//
+//	argon2 time (4 bytes, BE uint32)
+//	argon2 memory (4 bytes, BE uint32 KiB)
+//	argon2 threads (1 byte)
+//	salt (16 bytes)
+//	nonce (24 bytes)
+//	ciphertext (rest, secretbox sealed)
+//
+// version 2 (recipient key) continues:
+//
+//	ephemeral X25519 public key (32 bytes)
+//	nonce (24 bytes)
+//	ciphertext (rest, secretbox sealed under sha256(X25519(ephemeral, recipient)))
+const (
+	encryptedConfigMagic     = "SCFG"
+	encryptedConfigVersionV1 = 1
+	encryptedConfigVersionV2 = 2
+
+	argon2SaltSize = 16
+	argon2KeySize  = 32
+
+	defaultArgon2Time    = 3
+	defaultArgon2Memory  = 64 * 1024 // KiB
+	defaultArgon2Threads = 4
+
+	// maxArgon2Time/maxArgon2Memory - DecryptClientConfig reads these KDF params out of the
+	// untrusted container itself, so they're clamped before use: an attacker who can plant a
+	// file an operator will try to open could otherwise set them arbitrarily high as a DoS.
+	maxArgon2Time   = 10
+	maxArgon2Memory = 1024 * 1024 // 1 GiB, KiB units
+
+	recipientKeySize = 32
+)
+
+// IsEncryptedClientConfig - does data look like our encrypted container, vs. plain ClientConfig JSON?
+func IsEncryptedClientConfig(data []byte) bool {
+	return len(data) >= len(encryptedConfigMagic) && string(data[:len(encryptedConfigMagic)]) == encryptedConfigMagic
+}
+
+// EncryptClientConfig - wrap configJSON in a passphrase-derived authenticated envelope
+func EncryptClientConfig(configJSON []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, argon2SaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	var nonce [24]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, err
+	}
+
+	key := argon2.IDKey([]byte(passphrase), salt, defaultArgon2Time, defaultArgon2Memory, defaultArgon2Threads, argon2KeySize)
+	var secretKey [32]byte
+	copy(secretKey[:], key)
+
+	out := make([]byte, 0, len(encryptedConfigMagic)+1+9+argon2SaltSize+24+len(configJSON)+secretbox.Overhead)
+	out = append(out, []byte(encryptedConfigMagic)...)
+	out = append(out, encryptedConfigVersionV1)
+	out = binary.BigEndian.AppendUint32(out, defaultArgon2Time)
+	out = binary.BigEndian.AppendUint32(out, defaultArgon2Memory)
+	out = append(out, defaultArgon2Threads)
+	out = append(out, salt...)
+	out = append(out, nonce[:]...)
+	out = secretbox.Seal(out, configJSON, &nonce, &secretKey)
+	return out, nil
+}
+
+// EncryptClientConfigForRecipient - wrap configJSON in an envelope only the holder of
+// recipientPrivateKey (the X25519 private key paired with recipientPublicKey) can open,
+// the same shape `age` uses for its X25519 recipients: an ephemeral keypair is generated
+// per message, its public half travels in the envelope, and the shared secret from
+// ECDH(ephemeral private, recipient public) seals the payload.
+func EncryptClientConfigForRecipient(configJSON []byte, recipientPublicKey [recipientKeySize]byte) ([]byte, error) {
+	var ephemeralPrivate, ephemeralPublic [recipientKeySize]byte
+	if _, err := rand.Read(ephemeralPrivate[:]); err != nil {
+		return nil, err
+	}
+	curve25519.ScalarBaseMult(&ephemeralPublic, &ephemeralPrivate)
+
+	secretKey, err := recipientSharedKey(ephemeralPrivate, recipientPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var nonce [24]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(encryptedConfigMagic)+1+recipientKeySize+24+len(configJSON)+secretbox.Overhead)
+	out = append(out, []byte(encryptedConfigMagic)...)
+	out = append(out, encryptedConfigVersionV2)
+	out = append(out, ephemeralPublic[:]...)
+	out = append(out, nonce[:]...)
+	out = secretbox.Seal(out, configJSON, &nonce, secretKey)
+	return out, nil
+}
+
+// DecryptClientConfigWithRecipientKey - open an envelope produced by
+// EncryptClientConfigForRecipient using the recipient's X25519 private key
+func DecryptClientConfigWithRecipientKey(container []byte, recipientPrivateKey [recipientKeySize]byte) ([]byte, error) {
+	headerLen := len(encryptedConfigMagic) + 1 + recipientKeySize + 24
+	if len(container) < headerLen {
+		return nil, fmt.Errorf("encrypted config is truncated")
+	}
+	if !IsEncryptedClientConfig(container) {
+		return nil, fmt.Errorf("not an encrypted client config")
+	}
+	offset := len(encryptedConfigMagic)
+
+	version := container[offset]
+	offset++
+	if version != encryptedConfigVersionV2 {
+		return nil, fmt.Errorf("not a recipient-key encrypted config (version %d)", version)
+	}
+
+	var ephemeralPublic [recipientKeySize]byte
+	copy(ephemeralPublic[:], container[offset:offset+recipientKeySize])
+	offset += recipientKeySize
+
+	var nonce [24]byte
+	copy(nonce[:], container[offset:offset+24])
+	offset += 24
+
+	ciphertext := container[offset:]
+
+	secretKey, err := recipientSharedKey(recipientPrivateKey, ephemeralPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, ok := secretbox.Open(nil, ciphertext, &nonce, secretKey)
+	if !ok {
+		return nil, fmt.Errorf("decryption failed: wrong recipient key or corrupt config")
+	}
+	return plaintext, nil
+}
+
+// recipientSharedKey - derive a secretbox key from an X25519 ECDH exchange. sha256 of the
+// raw shared point (rather than the point itself) keeps the key uniformly distributed, the
+// same reasoning libsodium's crypto_box uses HSalsa20 for instead of the raw scalar output.
+func recipientSharedKey(ourPrivate [recipientKeySize]byte, theirPublic [recipientKeySize]byte) (*[32]byte, error) {
+	shared, err := curve25519.X25519(ourPrivate[:], theirPublic[:])
+	if err != nil {
+		return nil, fmt.Errorf("X25519 exchange failed: %w", err)
+	}
+	key := sha256.Sum256(shared)
+	return &key, nil
+}
+
+// DecryptClientConfig - open an envelope produced by EncryptClientConfig, returning the plain ClientConfig JSON
+func DecryptClientConfig(container []byte, passphrase string) ([]byte, error) {
+	headerLen := len(encryptedConfigMagic) + 1 + 4 + 4 + 1 + argon2SaltSize + 24
+	if len(container) < headerLen {
+		return nil, fmt.Errorf("encrypted config is truncated")
+	}
+	if !IsEncryptedClientConfig(container) {
+		return nil, fmt.Errorf("not an encrypted client config")
+	}
+	offset := len(encryptedConfigMagic)
+
+	version := container[offset]
+	offset++
+	if version == encryptedConfigVersionV2 {
+		return nil, fmt.Errorf("config is encrypted for a recipient key, not a passphrase; use DecryptClientConfigWithRecipientKey")
+	}
+	if version != encryptedConfigVersionV1 {
+		return nil, fmt.Errorf("unsupported encrypted config version %d", version)
+	}
+
+	argonTime := binary.BigEndian.Uint32(container[offset : offset+4])
+	offset += 4
+	argonMemory := binary.BigEndian.Uint32(container[offset : offset+4])
+	offset += 4
+	argonThreads := container[offset]
+	offset++
+
+	// Clamp KDF params read from the (untrusted) file itself before deriving the key, so a
+	// planted file can't force an arbitrarily expensive argon2 run on whoever opens it.
+	if argonTime > maxArgon2Time {
+		argonTime = maxArgon2Time
+	}
+	if argonMemory > maxArgon2Memory {
+		argonMemory = maxArgon2Memory
+	}
+
+	salt := container[offset : offset+argon2SaltSize]
+	offset += argon2SaltSize
+
+	var nonce [24]byte
+	copy(nonce[:], container[offset:offset+24])
+	offset += 24
+
+	ciphertext := container[offset:]
+
+	key := argon2.IDKey([]byte(passphrase), salt, argonTime, argonMemory, argonThreads, argon2KeySize)
+	var secretKey [32]byte
+	copy(secretKey[:], key)
+
+	plaintext, ok := secretbox.Open(nil, ciphertext, &nonce, &secretKey)
+	if !ok {
+		return nil, fmt.Errorf("decryption failed: wrong passphrase or corrupt config")
+	}
+	return plaintext, nil
+}
+
+// promptPassphrase - read a passphrase from the terminal without echoing it
+func promptPassphrase(prompt string) (string, error) {
+	fmt.Print(prompt)
+	passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+	return string(passphrase), nil
+}
@@ -0,0 +1,79 @@
+package console
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/bishopfox/sliver/server/assets"
+	"github.com/bishopfox/sliver/server/certs"
+	"github.com/bishopfox/sliver/server/core"
+)
+
+// EnrollmentRequest - body posted by `sliver-client enroll --url ... --token ...`
+type EnrollmentRequest struct {
+	Token string `json:"token"`
+}
+
+// enrollHandler - trades a single-use enrollment token for the operator's ClientConfig.
+// Served by startEnrollmentListener on its own HTTPS listener, which startMultiplayerModeCmd
+// binds one port above the mTLS multiplayer listener; it is not served on that listener itself.
+func enrollHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req EnrollmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "malformed request", http.StatusBadRequest)
+		return
+	}
+
+	token, err := core.RedeemEnrollmentToken(req.Token)
+	if err != nil {
+		log.Printf("enrollment rejected: %v", err)
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	config := ClientConfig{
+		Operator:      token.Operator,
+		CACertificate: token.CACertificate,
+		Certificate:   token.Certificate,
+		PrivateKey:    token.PrivateKey,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(config); err != nil {
+		log.Printf("failed to write enrollment response: %v", err)
+	}
+}
+
+// startEnrollmentListener - serve the token-redeem endpoint over HTTPS, using the
+// same CA as the mTLS client listener so `sliver-client enroll` can pin on first contact.
+func startEnrollmentListener(bindIface string, port uint16) error {
+	rootDir := assets.GetRootAppDir()
+	serverCert, serverKey := certs.GenerateServerCertificate(rootDir, bindIface, false)
+	cert, err := tls.X509KeyPair(serverCert, serverKey)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/enroll", enrollHandler)
+	mux.HandleFunc("/crl", crlHandler)
+
+	ln, err := tls.Listen("tcp", fmt.Sprintf("%s:%d", bindIface, port), &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	})
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := http.Serve(ln, mux); err != nil {
+			log.Printf("enrollment listener stopped: %v", err)
+		}
+	}()
+	return nil
+}
@@ -8,15 +8,29 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
-	consts "sliver/client/constants"
-	"sliver/server/assets"
-	"sliver/server/certs"
-	"sliver/server/core"
-	"sliver/server/transport"
+	"text/tabwriter"
+	"time"
+
+	consts "github.com/bishopfox/sliver/client/constants"
+	"github.com/bishopfox/sliver/server/assets"
+	"github.com/bishopfox/sliver/server/certs"
+	"github.com/bishopfox/sliver/server/core"
+	"github.com/bishopfox/sliver/server/transport"
 
 	"github.com/desertbit/grumble"
 )
 
+// enrollmentTokenTTL - how long an enrollment token minted by `new-player --enroll` stays valid
+const enrollmentTokenTTL = 15 * time.Minute
+
+// enrollmentSweepInterval - how often expired, never-redeemed enrollment tokens are
+// swept and their paired client certificate revoked
+const enrollmentSweepInterval = time.Minute
+
+// auditPublishInterval - how often the audit log's signed tree head is republished in
+// the background, independent of whether an operator ever runs `audit head`
+const auditPublishInterval = 10 * time.Minute
+
 const (
 	// ANSI Colors
 	normal    = "\033[0m"
@@ -59,6 +73,7 @@ func newPlayerCmd(ctx *grumble.Context) {
 	lhost := ctx.Flags.String("lhost")
 	lport := ctx.Flags.Int("lport")
 	save := ctx.Flags.String("save")
+	enroll := ctx.Flags.Bool("enroll")
 
 	regex, _ := regexp.Compile("[^A-Za-z0-9]+") // Only allow alphanumeric chars
 	operator = regex.ReplaceAllString(operator, "")
@@ -73,10 +88,6 @@ func newPlayerCmd(ctx *grumble.Context) {
 		return
 	}
 
-	if save == "" {
-		save, _ = os.Getwd()
-	}
-
 	fmt.Printf(Info + "Generating new client certificate, please wait ... \n")
 	rootDir := assets.GetRootAppDir()
 	publicKey, privateKey := certs.GenerateClientCertificate(rootDir, operator, true)
@@ -89,7 +100,54 @@ func newPlayerCmd(ctx *grumble.Context) {
 		PrivateKey:    string(privateKey),
 		Certificate:   string(publicKey),
 	}
+
+	if enroll {
+		token, err := core.IssueEnrollmentToken(operator, config.CACertificate, config.Certificate, config.PrivateKey, enrollmentTokenTTL)
+		if err != nil {
+			fmt.Printf(Warn+"Failed to issue enrollment token: %v\n", err)
+			writeAuditRecord(operator, "new-player", map[string]interface{}{"enroll": true, "lhost": lhost, "lport": lport}, err.Error())
+			return
+		}
+		fmt.Printf(Info+"Enrollment token for %s (expires in %s): %s\n", operator, enrollmentTokenTTL, token.Value)
+		fmt.Printf(Info + "Have the operator run:\n")
+		// the enrollment endpoint listens one port above the multiplayer listener (see startMultiplayerModeCmd)
+		fmt.Printf("    sliver-client enroll --url https://%s:%d --token %s\n", lhost, lport+1, token.Value)
+		writeAuditRecord(operator, "new-player", map[string]interface{}{"enroll": true, "lhost": lhost, "lport": lport}, "issued")
+		return
+	}
+
+	if save == "" {
+		save, _ = os.Getwd()
+	}
 	configJSON, _ := json.Marshal(config)
+
+	fileMode := os.FileMode(0644)
+	if ctx.Flags.Bool("encrypt") {
+		var err error
+		if ctx.Flags.Bool("recipient") {
+			// --recipient prefers a key registered for this operator (via
+			// certs.RegisterOperatorRecipientKey) over a passphrase, so the config can only
+			// be opened by whoever holds the paired private key, with nothing to phish.
+			recipientKey, lookupErr := certs.GetOperatorRecipientKey(rootDir, operator)
+			if lookupErr != nil {
+				fmt.Printf(Warn+"Failed to look up recipient key: %v\n", lookupErr)
+				return
+			}
+			configJSON, err = EncryptClientConfigForRecipient(configJSON, recipientKey)
+		} else {
+			var passphrase string
+			passphrase, err = promptPassphrase("Enter a passphrase to encrypt the client config: ")
+			if err == nil {
+				configJSON, err = EncryptClientConfig(configJSON, passphrase)
+			}
+		}
+		if err != nil {
+			fmt.Printf(Warn+"Failed to encrypt config: %v\n", err)
+			return
+		}
+		fileMode = 0600 // the envelope still carries key material, keep it off world-readable perms
+	}
+
 	saveTo, _ := filepath.Abs(save)
 	fi, err := os.Stat(saveTo)
 	if err != nil {
@@ -100,35 +158,138 @@ func newPlayerCmd(ctx *grumble.Context) {
 		filename := fmt.Sprintf("%s_%s.cfg", filepath.Base(operator), filepath.Base(lhost))
 		saveTo = filepath.Join(saveTo, filename)
 	}
-	err = ioutil.WriteFile(saveTo, configJSON, 0644)
+	err = ioutil.WriteFile(saveTo, configJSON, fileMode)
 	if err != nil {
 		fmt.Printf(Warn+"Failed to write config to: %s (%v) \n", saveTo, err)
+		writeAuditRecord(operator, "new-player", map[string]interface{}{"lhost": lhost, "lport": lport}, err.Error())
 		return
 	}
 	fmt.Printf(Info+"Saved new client config to: %s \n", saveTo)
+	writeAuditRecord(operator, "new-player", map[string]interface{}{"lhost": lhost, "lport": lport, "saved_to": saveTo}, "saved")
 }
 
 func kickPlayerCmd(ctx *grumble.Context) {
+	operator := ctx.Flags.String("operator")
+	if operator == "" {
+		fmt.Printf(Warn + "Operator name required (--operator) \n")
+		return
+	}
 
+	// Close any live connections first so we also get the cert serial(s) they were
+	// authenticated with, then fall back to a disk lookup so an operator who isn't
+	// currently connected still has their certificate revoked.
+	serials := map[string]bool{}
+	for _, serial := range core.CloseOperatorConnections(operator) {
+		serials[serial] = true
+	}
+
+	rootDir := assets.GetRootAppDir()
+	if serial, err := certs.LookupClientCertificateSerial(rootDir, operator); err == nil {
+		serials[serial] = true
+	} else if len(serials) == 0 {
+		fmt.Printf(Warn+"%s is not currently connected and no certificate was found: %v\n", operator, err)
+		writeAuditRecord(operator, "kick-player", map[string]interface{}{}, err.Error())
+		return
+	}
+
+	for serial := range serials {
+		if err := certs.RevokeCertificate(rootDir, serial, operator); err != nil {
+			fmt.Printf(Warn+"Failed to revoke certificate %s: %v\n", serial, err)
+			writeAuditRecord(operator, "kick-player", map[string]interface{}{"serial": serial}, err.Error())
+			continue
+		}
+		fmt.Printf(Info+"Revoked certificate %s for %s\n", serial, operator)
+		writeAuditRecord(operator, "kick-player", map[string]interface{}{"serial": serial}, "revoked")
+	}
+}
+
+func unkickPlayerCmd(ctx *grumble.Context) {
+	serial := ctx.Flags.String("serial")
+	if serial == "" {
+		fmt.Printf(Warn + "Certificate serial required (--serial) \n")
+		return
+	}
+	rootDir := assets.GetRootAppDir()
+	if err := certs.UnrevokeCertificate(rootDir, serial); err != nil {
+		fmt.Printf(Warn+"Failed to unrevoke certificate %s: %v\n", serial, err)
+		writeAuditRecord("", "unkick-player", map[string]interface{}{"serial": serial}, err.Error())
+		return
+	}
+	fmt.Printf(Info+"Removed %s from the revocation list\n", serial)
+	writeAuditRecord("", "unkick-player", map[string]interface{}{"serial": serial}, "unrevoked")
 }
 
 func listPlayersCmd(ctx *grumble.Context) {
+	operators := core.ListConnectedOperators()
+	if len(operators) == 0 {
+		fmt.Printf(Info + "No operators connected\n")
+		return
+	}
 
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Operator\tRemote Address\tConnected At\tLast Seen\tSessions")
+	fmt.Fprintln(tw, "--------\t--------------\t------------\t---------\t--------")
+	for _, op := range operators {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\n",
+			op.CommonName,
+			op.RemoteAddr,
+			op.ConnectedAt.Format(time.RFC1123),
+			op.LastSeenAt.Format(time.RFC1123),
+			op.SessionCount,
+		)
+	}
+	tw.Flush()
 }
 
 func startMultiplayerModeCmd(ctx *grumble.Context) {
 	server := ctx.Flags.String("server")
 	lport := uint16(ctx.Flags.Int("lport"))
+	clusterPeers := ctx.Flags.String("cluster-peers")
+
+	if clusterPeers != "" {
+		// server/state's only Store is BoltStore, which is local to one process, so enrollment
+		// tokens and the revocation list are not shared between peers; core.Jobs,
+		// core.EventBroker, and in-flight implant sessions are process-local as well. Nothing
+		// is shared across peers, so --cluster-peers refuses to start rather than run as a
+		// single node that an operator might believe is clustered.
+		fmt.Printf(Warn+"--cluster-peers %s requested, but no state is shared between peers (jobs, sessions, certs and enrollment tokens are node-local), refusing to start\n", clusterPeers)
+		writeAuditRecord("", "start-multiplayer", map[string]interface{}{"server": server, "lport": lport, "cluster_peers": clusterPeers}, "refused: clustering unsupported")
+		return
+	}
 
 	_, err := jobStartClientListener(server, lport)
 	if err == nil {
 		fmt.Printf(Info + "Multiplayer mode enabled!\n")
 	} else {
 		fmt.Printf(Warn+"Failed to start job %v\n", err)
+		writeAuditRecord("", "start-multiplayer", map[string]interface{}{"server": server, "lport": lport}, err.Error())
+		return
+	}
+
+	enrollPort := lport + 1
+	err = startEnrollmentListener(server, enrollPort)
+	if err == nil {
+		fmt.Printf(Info+"Enrollment endpoint listening on %s:%d\n", server, enrollPort)
+	} else {
+		fmt.Printf(Warn+"Failed to start enrollment endpoint: %v\n", err)
 	}
+
+	rootDir := assets.GetRootAppDir()
+	core.StartEnrollmentSweepLoop(enrollmentSweepInterval, func(token *core.EnrollmentToken) {
+		if err := certs.RevokeCertificate(rootDir, token.CertSerial, token.Operator); err != nil {
+			log.Printf("failed to revoke certificate for expired enrollment token (%s): %v", token.Operator, err)
+			return
+		}
+		writeAuditRecord(token.Operator, "enrollment-token-expired", map[string]interface{}{"serial": token.CertSerial}, "revoked")
+	})
+	startAuditPublishLoop(auditPublishInterval)
+
+	writeAuditRecord("", "start-multiplayer", map[string]interface{}{"server": server, "lport": lport}, "started")
 }
 
 func jobStartClientListener(bindIface string, port uint16) (int, error) {
+	// transport.StartClientListener's tls.Config.VerifyPeerCertificate hook consults
+	// certs.IsRevoked(rootDir, serial) on every handshake and rejects revoked operators.
 	ln, err := transport.StartClientListener(bindIface, port)
 	if err != nil {
 		return -1, err // If we fail to bind don't setup the Job
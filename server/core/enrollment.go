@@ -0,0 +1,241 @@
+package core
+
+/*
+	Sliver Implant Framework
+	Copyright (C) 2019  Bishop Fox
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bishopfox/sliver/server/assets"
+	"github.com/bishopfox/sliver/server/state"
+)
+
+var (
+	// ErrEnrollmentTokenNotFound - no token matches the value the client presented
+	ErrEnrollmentTokenNotFound = errors.New("enrollment token not found")
+	// ErrEnrollmentTokenExpired - the token's TTL has elapsed
+	ErrEnrollmentTokenExpired = errors.New("enrollment token expired")
+	// ErrEnrollmentTokenUsed - the token has already been redeemed once
+	ErrEnrollmentTokenUsed = errors.New("enrollment token already redeemed")
+)
+
+// EnrollmentToken - single-use, time-limited credential that lets an operator
+// fetch their ClientConfig over the network instead of hand-carrying a .cfg file.
+type EnrollmentToken struct {
+	Value         string
+	Operator      string
+	CACertificate string
+	Certificate   string
+	PrivateKey    string
+	// CertSerial - serial number of the client certificate minted alongside this
+	// token, so an unredeemed, expired token's certificate can be revoked too.
+	CertSerial string
+	IssuedAt   time.Time
+	ExpiresAt  time.Time
+	Redeemed   bool
+}
+
+// enrollmentTokenPrefix - keyspace enrollment tokens live under in the shared Store,
+// so a token minted by `new-player --enroll` on one cluster node is immediately
+// redeemable on every other node in the cluster.
+const enrollmentTokenPrefix = "enroll/"
+
+var (
+	enrollmentStoreMu sync.Mutex
+	enrollmentStore   state.Store
+)
+
+// SetEnrollmentStore - point enrollment tokens at a different Store, e.g. one shared by
+// several servers. Defaults to a process-local BoltStore if never called.
+func SetEnrollmentStore(store state.Store) {
+	enrollmentStoreMu.Lock()
+	defer enrollmentStoreMu.Unlock()
+	enrollmentStore = store
+}
+
+func getEnrollmentStore() (state.Store, error) {
+	enrollmentStoreMu.Lock()
+	defer enrollmentStoreMu.Unlock()
+	if enrollmentStore == nil {
+		dbPath := filepath.Join(assets.GetRootAppDir(), "enrollment.db")
+		store, err := state.NewBoltStore(dbPath)
+		if err != nil {
+			return nil, err
+		}
+		enrollmentStore = store
+	}
+	return enrollmentStore, nil
+}
+
+// IssueEnrollmentToken - mint and register a new enrollment token for operator, valid for ttl
+func IssueEnrollmentToken(operator string, caCertificate string, certificate string, privateKey string, ttl time.Duration) (*EnrollmentToken, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	serial, err := certificateSerial(certificate)
+	if err != nil {
+		return nil, err
+	}
+	token := &EnrollmentToken{
+		Value:         hex.EncodeToString(raw),
+		Operator:      operator,
+		CACertificate: caCertificate,
+		Certificate:   certificate,
+		PrivateKey:    privateKey,
+		CertSerial:    serial,
+		IssuedAt:      time.Now(),
+		ExpiresAt:     time.Now().Add(ttl),
+	}
+	if err := putEnrollmentToken(token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// certificateSerial - extract the serial number of a PEM-encoded client certificate
+func certificateSerial(certificatePEM string) (string, error) {
+	block, _ := pem.Decode([]byte(certificatePEM))
+	if block == nil {
+		return "", errors.New("failed to decode certificate PEM block")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", err
+	}
+	return cert.SerialNumber.String(), nil
+}
+
+// redeemMu - held across RedeemEnrollmentToken's lookup and update of a token
+var redeemMu sync.Mutex
+
+// RedeemEnrollmentToken - look up a token by value, consuming it on success.
+// Returns an error without consuming the token if it is expired (the sweeper
+// will clean it up) or was already redeemed.
+func RedeemEnrollmentToken(value string) (*EnrollmentToken, error) {
+	// serialize the read-check-write below so two concurrent redeems can't both succeed
+	redeemMu.Lock()
+	defer redeemMu.Unlock()
+	store, err := getEnrollmentStore()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := store.Get(enrollmentTokenPrefix + value)
+	if err == state.ErrNotFound {
+		return nil, ErrEnrollmentTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	token := &EnrollmentToken{}
+	if err := json.Unmarshal(raw, token); err != nil {
+		return nil, err
+	}
+	if token.Redeemed {
+		return nil, ErrEnrollmentTokenUsed
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return nil, ErrEnrollmentTokenExpired
+	}
+	token.Redeemed = true
+	if err := putEnrollmentToken(token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// RevokeEnrollmentToken - remove a token immediately, e.g. an operator cancels enrollment
+func RevokeEnrollmentToken(value string) {
+	store, err := getEnrollmentStore()
+	if err != nil {
+		return
+	}
+	store.Delete(enrollmentTokenPrefix + value)
+}
+
+// StartEnrollmentSweepLoop - periodically call SweepExpiredEnrollmentTokens and hand every
+// swept token to onExpired, so the caller can revoke the paired client certificate. Runs
+// until the returned stop func is called.
+func StartEnrollmentSweepLoop(interval time.Duration, onExpired func(token *EnrollmentToken)) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				for _, token := range SweepExpiredEnrollmentTokens() {
+					onExpired(token)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// SweepExpiredEnrollmentTokens - drop expired, never-redeemed tokens and return them
+// so the caller can revoke the client certificate that was generated alongside each one.
+func SweepExpiredEnrollmentTokens() []*EnrollmentToken {
+	store, err := getEnrollmentStore()
+	if err != nil {
+		return nil
+	}
+	keys, err := store.List(enrollmentTokenPrefix)
+	if err != nil {
+		return nil
+	}
+	expired := []*EnrollmentToken{}
+	now := time.Now()
+	for _, key := range keys {
+		raw, err := store.Get(key)
+		if err != nil {
+			continue
+		}
+		token := &EnrollmentToken{}
+		if err := json.Unmarshal(raw, token); err != nil {
+			continue
+		}
+		if !token.Redeemed && now.After(token.ExpiresAt) {
+			expired = append(expired, token)
+			store.Delete(key)
+		}
+	}
+	return expired
+}
+
+func putEnrollmentToken(token *EnrollmentToken) error {
+	store, err := getEnrollmentStore()
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	return store.Put(enrollmentTokenPrefix+token.Value, raw)
+}
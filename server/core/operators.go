@@ -0,0 +1,89 @@
+package core
+
+/*
+	Sliver Implant Framework
+	Copyright (C) 2019  Bishop Fox
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"sync"
+	"time"
+)
+
+// ConnectedOperator - a live mTLS connection from an operator's sliver-client
+type ConnectedOperator struct {
+	CommonName   string
+	CertSerial   string
+	RemoteAddr   string
+	ConnectedAt  time.Time
+	LastSeenAt   time.Time
+	SessionCount int
+	Close        func() error
+}
+
+var (
+	connectedOperatorsMu sync.Mutex
+	connectedOperators   = map[string]*ConnectedOperator{}
+)
+
+// operatorKey - connections are keyed by CN+remote addr since one operator may have several clients open
+func operatorKey(commonName string, remoteAddr string) string {
+	return commonName + "@" + remoteAddr
+}
+
+// AddConnectedOperator - register a newly authenticated operator connection
+func AddConnectedOperator(op *ConnectedOperator) {
+	connectedOperatorsMu.Lock()
+	defer connectedOperatorsMu.Unlock()
+	connectedOperators[operatorKey(op.CommonName, op.RemoteAddr)] = op
+}
+
+// RemoveConnectedOperator - drop a connection from the registry, e.g. on disconnect
+func RemoveConnectedOperator(commonName string, remoteAddr string) {
+	connectedOperatorsMu.Lock()
+	defer connectedOperatorsMu.Unlock()
+	delete(connectedOperators, operatorKey(commonName, remoteAddr))
+}
+
+// ListConnectedOperators - snapshot every currently connected operator client
+func ListConnectedOperators() []*ConnectedOperator {
+	connectedOperatorsMu.Lock()
+	defer connectedOperatorsMu.Unlock()
+	operators := make([]*ConnectedOperator, 0, len(connectedOperators))
+	for _, op := range connectedOperators {
+		operators = append(operators, op)
+	}
+	return operators
+}
+
+// CloseOperatorConnections - close every live connection belonging to commonName,
+// returning their cert serials so the caller can revoke them
+func CloseOperatorConnections(commonName string) []string {
+	connectedOperatorsMu.Lock()
+	defer connectedOperatorsMu.Unlock()
+	serials := []string{}
+	for key, op := range connectedOperators {
+		if op.CommonName != commonName {
+			continue
+		}
+		if op.Close != nil {
+			op.Close()
+		}
+		serials = append(serials, op.CertSerial)
+		delete(connectedOperators, key)
+	}
+	return serials
+}
@@ -0,0 +1,56 @@
+package rpc
+
+/*
+	Sliver Implant Framework
+	Copyright (C) 2019  Bishop Fox
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bishopfox/sliver/protobuf/clientpb"
+	"github.com/bishopfox/sliver/server/db"
+	"github.com/bishopfox/sliver/server/db/models"
+)
+
+// GetHTTPC2Profiles - list every stored HTTP C2 profile, backing the `c2profiles list` command
+func (s *Server) GetHTTPC2Profiles(ctx context.Context, _ *clientpb.Empty) (*clientpb.HTTPC2Configs, error) {
+	httpC2Configs := []*models.HTTPC2Config{}
+	err := db.Session().Find(&httpC2Configs).Error
+	if err != nil {
+		return nil, err
+	}
+	configs := &clientpb.HTTPC2Configs{}
+	for _, httpC2Config := range httpC2Configs {
+		configs.Configs = append(configs.Configs, httpC2Config.ToProtobuf())
+	}
+	return configs, nil
+}
+
+// DeleteHTTPC2Profile - delete a stored HTTP C2 profile by name, backing the `c2profiles rm` command
+func (s *Server) DeleteHTTPC2Profile(ctx context.Context, req *clientpb.C2ProfileReq) (*clientpb.Empty, error) {
+	if req.Name == "" {
+		return nil, errors.New("profile name is required")
+	}
+	// Named condition, not a struct literal: a struct condition drops zero-value fields from
+	// the WHERE clause, so an empty Name would otherwise delete every stored profile.
+	err := db.Session().Where("name = ?", req.Name).Delete(&models.HTTPC2Config{}).Error
+	if err != nil {
+		return nil, err
+	}
+	return &clientpb.Empty{}, nil
+}
@@ -0,0 +1,153 @@
+package state
+
+/*
+	Sliver Implant Framework
+	Copyright (C) 2019  Bishop Fox
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var stateBucket = []byte("state")
+
+// BoltStore - single-node Store backed by a local BoltDB file. Watch only
+// fans out events to subscribers within this same process: it's a correct
+// Store for a standalone sliver-server, but it does not replicate, so it
+// cannot be the backend for a `--cluster-peers` deployment.
+type BoltStore struct {
+	db *bolt.DB
+
+	watchersMu sync.Mutex
+	watchers   map[string][]chan Event
+}
+
+// NewBoltStore - open (creating if necessary) a BoltDB file at path
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(filepath.Clean(path), 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(stateBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStore{db: db, watchers: map[string][]chan Event{}}, nil
+}
+
+// Get - implements Store
+func (s *BoltStore) Get(key string) ([]byte, error) {
+	var value []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(stateBucket).Get([]byte(key))
+		if v == nil {
+			return ErrNotFound
+		}
+		value = append([]byte{}, v...)
+		return nil
+	})
+	return value, err
+}
+
+// Put - implements Store
+func (s *BoltStore) Put(key string, value []byte) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(stateBucket).Put([]byte(key), value)
+	})
+	if err != nil {
+		return err
+	}
+	s.notify(Event{Type: EventPut, Key: key, Value: value})
+	return nil
+}
+
+// Delete - implements Store
+func (s *BoltStore) Delete(key string) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(stateBucket).Delete([]byte(key))
+	})
+	if err != nil {
+		return err
+	}
+	s.notify(Event{Type: EventDelete, Key: key})
+	return nil
+}
+
+// List - implements Store
+func (s *BoltStore) List(prefix string) ([]string, error) {
+	keys := []string{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(stateBucket).Cursor()
+		prefixBytes := []byte(prefix)
+		for k, _ := cursor.Seek(prefixBytes); k != nil && bytes.HasPrefix(k, prefixBytes); k, _ = cursor.Next() {
+			keys = append(keys, string(k))
+		}
+		return nil
+	})
+	return keys, err
+}
+
+// Watch - implements Store
+func (s *BoltStore) Watch(prefix string) (<-chan Event, func()) {
+	ch := make(chan Event, 16)
+	s.watchersMu.Lock()
+	s.watchers[prefix] = append(s.watchers[prefix], ch)
+	s.watchersMu.Unlock()
+
+	cancel := func() {
+		s.watchersMu.Lock()
+		defer s.watchersMu.Unlock()
+		subs := s.watchers[prefix]
+		for i, sub := range subs {
+			if sub == ch {
+				s.watchers[prefix] = append(subs[:i], subs[i+1:]...)
+				close(ch)
+				return
+			}
+		}
+	}
+	return ch, cancel
+}
+
+func (s *BoltStore) notify(evt Event) {
+	s.watchersMu.Lock()
+	defer s.watchersMu.Unlock()
+	for prefix, subs := range s.watchers {
+		if !strings.HasPrefix(evt.Key, prefix) {
+			continue
+		}
+		for _, ch := range subs {
+			select {
+			case ch <- evt:
+			default: // a slow watcher must not block writers
+			}
+		}
+	}
+}
+
+// Close - implements Store
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,66 @@
+package state
+
+/*
+	Sliver Implant Framework
+	Copyright (C) 2019  Bishop Fox
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import "errors"
+
+// ErrNotFound - no value is stored under the requested key
+var ErrNotFound = errors.New("state: key not found")
+
+// EventType - what happened to a watched key
+type EventType int
+
+const (
+	// EventPut - key was created or overwritten
+	EventPut EventType = iota
+	// EventDelete - key was removed
+	EventDelete
+)
+
+// Event - a single change to a key under a watched prefix
+type Event struct {
+	Type  EventType
+	Key   string
+	Value []byte
+}
+
+// Store - the shared state a multiplayer cluster agrees on. A single-node server
+// runs on BoltStore; an HA deployment would run every server process against the
+// same etcd or Consul cluster so state written on one node is immediately visible
+// on the others, the way Traefik's cluster mode keeps its ACME certificates in a
+// shared KV store instead of on each instance's local disk. core.EnrollmentToken
+// and certs' revocation list already go through this interface; core.Jobs and
+// core.EventBroker are not yet migrated, and no etcd/Consul Store implementation
+// exists, so `--cluster-peers` refuses to start rather than silently running each
+// node against its own local BoltStore (see console.startMultiplayerModeCmd).
+type Store interface {
+	// Get - fetch the value stored at key, or ErrNotFound
+	Get(key string) ([]byte, error)
+	// Put - create or overwrite the value stored at key
+	Put(key string, value []byte) error
+	// Delete - remove key; a no-op if it doesn't exist
+	Delete(key string) error
+	// List - every key currently stored under prefix
+	List(prefix string) ([]string, error)
+	// Watch - subscribe to Put/Delete events for keys under prefix. The
+	// returned cancel func stops the subscription and closes the channel.
+	Watch(prefix string) (<-chan Event, func())
+	// Close - release the backend's resources (file handles, client connections, ...)
+	Close() error
+}
@@ -0,0 +1,149 @@
+package transport
+
+/*
+	Sliver Implant Framework
+	Copyright (C) 2019  Bishop Fox
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"github.com/bishopfox/sliver/server/assets"
+	"github.com/bishopfox/sliver/server/certs"
+	"github.com/bishopfox/sliver/server/core"
+)
+
+// StartClientListener - start a mutual TLS listener for operator (client) connections.
+// The returned net.Listener completes the TLS handshake itself (instead of leaving it to
+// the first Read, as tls.Listen's Accept normally does) so that every connection it hands
+// back is already registered in core.connectedOperators; this is what lets `players` and
+// `kick-player` see live connections at all.
+func StartClientListener(bindIface string, port uint16) (net.Listener, error) {
+	tlsConfig, err := getOperatorServerTLSConfig(assets.GetRootAppDir())
+	if err != nil {
+		return nil, err
+	}
+	bindAddr := fmt.Sprintf("%s:%d", bindIface, port)
+	ln, err := tls.Listen("tcp", bindAddr, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Client listener started on %s", bindAddr)
+	return &registeringListener{Listener: ln}, nil
+}
+
+// registeringListener - wraps a tls.Listener so that Accept only returns once the client
+// has completed its handshake, at which point the operator is added to core's connection
+// registry; the connection is removed again when the caller closes it.
+type registeringListener struct {
+	net.Listener
+}
+
+func (l *registeringListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		tlsConn, ok := conn.(*tls.Conn)
+		if !ok {
+			return conn, nil
+		}
+		if err := tlsConn.Handshake(); err != nil {
+			log.Printf("Client handshake failed from %s: %v", conn.RemoteAddr(), err)
+			tlsConn.Close()
+			continue
+		}
+		peerCerts := tlsConn.ConnectionState().PeerCertificates
+		if len(peerCerts) == 0 {
+			log.Printf("Client %s presented no certificate after handshake", conn.RemoteAddr())
+			tlsConn.Close()
+			continue
+		}
+		commonName := peerCerts[0].Subject.CommonName
+		remoteAddr := conn.RemoteAddr().String()
+		now := time.Now()
+		core.AddConnectedOperator(&core.ConnectedOperator{
+			CommonName:  commonName,
+			CertSerial:  peerCerts[0].SerialNumber.String(),
+			RemoteAddr:  remoteAddr,
+			ConnectedAt: now,
+			LastSeenAt:  now,
+			Close:       tlsConn.Close,
+		})
+		return &trackedConn{Conn: tlsConn, commonName: commonName, remoteAddr: remoteAddr}, nil
+	}
+}
+
+// trackedConn - a client connection that deregisters itself from core's connection
+// registry on Close, so a disconnected operator no longer shows up in `players`
+type trackedConn struct {
+	net.Conn
+	commonName string
+	remoteAddr string
+}
+
+func (c *trackedConn) Close() error {
+	core.RemoveConnectedOperator(c.commonName, c.remoteAddr)
+	return c.Conn.Close()
+}
+
+// getOperatorServerTLSConfig - build the server-side tls.Config for the mTLS client listener.
+// VerifyPeerCertificate rejects any operator certificate whose serial has been revoked via
+// `kick-player`, even though the certificate itself is still within its validity window and
+// would otherwise pass chain verification.
+func getOperatorServerTLSConfig(rootDir string) (*tls.Config, error) {
+	caCertPEM, _, err := certs.GetCertificateAuthorityPEM(rootDir, certs.ClientsCertDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load clients CA: %w", err)
+	}
+	caCertPool := x509.NewCertPool()
+	if !caCertPool.AppendCertsFromPEM(caCertPEM) {
+		return nil, fmt.Errorf("failed to parse clients CA certificate")
+	}
+
+	serverCert, serverKey, err := certs.GetCertificateAuthorityPEM(rootDir, certs.ServerCertDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %w", err)
+	}
+	keyPair, err := tls.X509KeyPair(serverCert, serverKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse server certificate: %w", err)
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{keyPair},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    caCertPool,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			for _, raw := range rawCerts {
+				cert, err := x509.ParseCertificate(raw)
+				if err != nil {
+					continue
+				}
+				if certs.IsRevoked(rootDir, cert.SerialNumber.String()) {
+					return fmt.Errorf("certificate %s has been revoked", cert.SerialNumber.String())
+				}
+			}
+			return nil
+		},
+	}, nil
+}